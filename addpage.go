@@ -0,0 +1,111 @@
+package main
+
+// addpage scaffolds a new page, without zones, into an existing yaml
+// config. The page is inserted as text at the end of the pages list so
+// that the comments and formatting of the rest of the file are kept.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// AddPageOptions describe the page added by the add-page command.
+type AddPageOptions struct {
+	URL          string
+	Title        string
+	ImagePath    string
+	SkipValidate bool // write the config without validating it
+}
+
+// addPageYAML returns the yaml config b with a page for the url, title
+// and image of opts, with an empty Zones list, appended to its pages.
+func addPageYAML(b []byte, opts AddPageOptions) ([]byte, error) {
+	file, err := parser.ParseBytes(b, 0)
+	if err != nil {
+		return nil, fmt.Errorf("config parse error: %w", err)
+	}
+
+	// Find the pages sequence and the line of the top level key
+	// following it, if any.
+	var pages *ast.SequenceNode
+	nextLine := 0
+	for _, doc := range file.Docs {
+		root, ok := doc.Body.(*ast.MappingNode)
+		if !ok {
+			continue
+		}
+		for i, mv := range root.Values {
+			if mv.Key.String() != "pages" {
+				continue
+			}
+			if pages, ok = mv.Value.(*ast.SequenceNode); !ok || pages.IsFlowStyle {
+				return nil, errors.New("config pages must be a block list")
+			}
+			if i+1 < len(root.Values) {
+				nextLine = root.Values[i+1].Key.GetToken().Position.Line
+			}
+		}
+	}
+	if pages == nil {
+		return nil, errors.New("config has no pages list")
+	}
+
+	// Write the page in the style of the example config, indented to
+	// match the existing pages.
+	indent := strings.Repeat(" ", pages.Start.Position.Column-1)
+	var entry bytes.Buffer
+	fmt.Fprintf(&entry, "%s-\n", indent)
+	fmt.Fprintf(&entry, "%s  URL: %q\n", indent, opts.URL)
+	fmt.Fprintf(&entry, "%s  Title: %q\n", indent, opts.Title)
+	fmt.Fprintf(&entry, "%s  ImagePath: %q\n", indent, opts.ImagePath)
+	fmt.Fprintf(&entry, "%s  Zones: []\n", indent)
+
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+
+	// Insert before the next key, and any blank or comment lines above
+	// it, or at the end of the file.
+	at := len(lines)
+	if nextLine > 0 {
+		at = nextLine - 1
+		for at > 0 {
+			trimmed := strings.TrimSpace(lines[at-1])
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				break
+			}
+			at--
+		}
+	}
+	out := strings.Join(lines[:at], "") + entry.String() + strings.Join(lines[at:], "")
+	return []byte(out), nil
+}
+
+// withoutNoZones returns err without any CodeNoZones problems, which
+// are expected for scaffolded pages, or nil if no others remain.
+func withoutNoZones(err error) error {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else if err != nil {
+		errs = []error{err}
+	}
+	var kept []error
+	for _, e := range errs {
+		var eic ErrInvalidConfig
+		if errors.As(e, &eic) && eic.Code == CodeNoZones {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return errors.Join(kept...)
+}
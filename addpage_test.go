@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddPageYAML(t *testing.T) {
+
+	opts := AddPageOptions{URL: "/foo", Title: "Foo", ImagePath: "images/foo.jpg"}
+	entry := `  -
+    URL: "/foo"
+    Title: "Foo"
+    ImagePath: "images/foo.jpg"
+    Zones: []
+`
+	pages := `pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367 # aligned
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/home"
+`
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "pages last",
+			config: "---\n# assets\nassetsDir: \"assets\"\n" + pages,
+			want:   "---\n# assets\nassetsDir: \"assets\"\n" + pages + entry,
+		},
+		{
+			name:   "pages last without newline",
+			config: strings.TrimSuffix(pages, "\n"),
+			want:   pages + entry,
+		},
+		{
+			name:   "pages followed by a key",
+			config: pages + "\n# favicon\nfavicon: \"static/favicon.svg\"\n",
+			want:   pages + entry + "\n# favicon\nfavicon: \"static/favicon.svg\"\n",
+		},
+		{
+			name:    "no pages",
+			config:  "assetsDir: \"assets\"\n",
+			wantErr: "no pages list",
+		},
+		{
+			name:    "flow pages",
+			config:  "pages: []\n",
+			wantErr: "block list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addPageYAML([]byte(tt.config), opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("config mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppAddPage(t *testing.T) {
+
+	app := App{}
+	configFile := makeOKConfig(t, true)
+	defer func() { _ = os.Remove(configFile) }()
+
+	// An image which does not exist fails validation.
+	err := app.AddPage(configFile, AddPageOptions{URL: "/foo", Title: "Foo", ImagePath: "images/foo.jpg"})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected image not found error, got %v", err)
+	}
+
+	// Pages without zones are accepted, including on a second add.
+	for _, url := range []string{"/foo", "/bar"} {
+		err = app.AddPage(configFile, AddPageOptions{URL: url, Title: "Foo", ImagePath: "images/home.jpg"})
+		if err != nil {
+			t.Fatalf("add %s error: %v", url, err)
+		}
+	}
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := parseConfig(b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(c.Pages), 4; got != want {
+		t.Errorf("got %d pages want %d", got, want)
+	}
+
+	// SkipValidate writes the page regardless.
+	err = app.AddPage(configFile, AddPageOptions{URL: "/baz", Title: "Baz", ImagePath: "images/baz.jpg", SkipValidate: true})
+	if err != nil {
+		t.Fatalf("skip validate error: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+// allowext restricts the files served from the static directory to an
+// allowlist of extensions, so that stray files, such as an
+// accidentally copied .env file, are not served from public demos.
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// allowedExtensions wraps the static file server handler to respond
+// with a 404 to requests for files whose extension, such as ".css", is
+// not in exts, which must be lower case. Directory listings, having no
+// extension, are not served.
+func allowedExtensions(exts []string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !extensionAllowed(exts, r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// extensionAllowed reports if the extension of the slash separated
+// path name is in exts.
+func extensionAllowed(exts []string, name string) bool {
+	return slices.Contains(exts, strings.ToLower(path.Ext(name)))
+}
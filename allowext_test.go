@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerStaticAllowedExtensions(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		exts   []string
+		path   string
+		status int
+	}{
+		{"unrestricted", nil, "/static/zones.js", http.StatusOK},
+		{"allowed", []string{".css", ".js"}, "/static/zones.js", http.StatusOK},
+		{"disallowed", []string{".css"}, "/static/zones.js", http.StatusNotFound},
+		{"directory", []string{".css"}, "/static/", http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := initServer(t)
+			s.staticExts = tt.exts
+
+			handler, err := s.buildHandler()
+			if err != nil {
+				t.Fatal("buildHander error:", err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if got, want := w.Code, tt.status; got != want {
+				t.Errorf("got status %d want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+// analyze summarises the page visits recorded in a combined format
+// access log, as written by the server, for example after a usability
+// session.
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"text/tabwriter"
+)
+
+// accessLogRequestRe matches the request line and status of a combined
+// format access log line.
+var accessLogRequestRe = regexp.MustCompile(`"(\S+) (\S+) [^"]*" (\d{3}) `)
+
+// pageVisits is the number of visits to a page.
+type pageVisits struct {
+	URL    string
+	Title  string
+	Visits int
+}
+
+// countVisits counts the successful GET requests in the combined format
+// access log r for each page of c. Requests for other paths, such as
+// images, are ignored. The pages are returned most visited first, with
+// pages having the same number of visits in config order.
+func countVisits(c *config, r io.Reader) ([]pageVisits, error) {
+	counts := make([]int, len(c.Pages))
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := accessLogRequestRe.FindStringSubmatch(scanner.Text())
+		if m == nil || m[1] != http.MethodGet {
+			continue
+		}
+		if m[3] != "200" && m[3] != "304" {
+			continue
+		}
+		u, err := url.Parse(m[2])
+		if err != nil {
+			continue
+		}
+		if ii, ok := c.pageForURL(u.Path); ok {
+			counts[ii]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("access log read error: %w", err)
+	}
+	visits := make([]pageVisits, len(c.Pages))
+	for ii, p := range c.Pages {
+		visits[ii] = pageVisits{URL: p.URL, Title: p.Title, Visits: counts[ii]}
+	}
+	slices.SortStableFunc(visits, func(a, b pageVisits) int {
+		return cmp.Compare(b.Visits, a.Visits)
+	})
+	return visits, nil
+}
+
+// writeVisits writes visits to w as a ranked table.
+func writeVisits(w io.Writer, visits []pageVisits) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tVISITS\tURL\tTITLE")
+	for i, v := range visits {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", i+1, v.Visits, v.URL, v.Title)
+	}
+	return tw.Flush()
+}
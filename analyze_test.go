@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testAccessLog = `127.0.0.1 - - [14/Oct/2026:10:00:00 +0000] "GET /home HTTP/1.1" 200 1024 "" "Mozilla/5.0"
+127.0.0.1 - - [14/Oct/2026:10:00:01 +0000] "GET /images/home.jpg HTTP/1.1" 200 81854 "http://127.0.0.1:8000/home" "Mozilla/5.0"
+127.0.0.1 - - [14/Oct/2026:10:00:02 +0000] "GET /detail?inline=1 HTTP/1.1" 200 1024 "" "Mozilla/5.0"
+127.0.0.1 - - [14/Oct/2026:10:00:03 +0000] "GET /detail HTTP/1.1" 304 0 "" "Mozilla/5.0"
+127.0.0.1 - - [14/Oct/2026:10:00:04 +0000] "GET /detail HTTP/1.1" 200 1024 "" "Mozilla/5.0"
+127.0.0.1 - - [14/Oct/2026:10:00:05 +0000] "POST /home HTTP/1.1" 200 1024 "" "Mozilla/5.0"
+127.0.0.1 - - [14/Oct/2026:10:00:06 +0000] "GET /home HTTP/1.1" 500 10 "" "Mozilla/5.0"
+not a log line
+`
+
+func TestCountVisits(t *testing.T) {
+	cfg := initServerConfig(t)
+	visits, err := countVisits(cfg, strings.NewReader(testAccessLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pageVisits{
+		{URL: "/detail", Title: "Detail", Visits: 3},
+		{URL: "/home", Title: "Home", Visits: 1},
+	}
+	if diff := cmp.Diff(want, visits); diff != "" {
+		t.Errorf("visits mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteVisits(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeVisits(&buf, []pageVisits{
+		{URL: "/detail", Title: "Detail", Visits: 3},
+		{URL: "/home", Title: "Home", Visits: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `RANK  VISITS  URL      TITLE
+1     3       /detail  Detail
+2     0       /home    Home
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("table mismatch (-want +got):\n%s", diff)
+	}
+}
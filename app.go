@@ -1,18 +1,60 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
+
+	"rsc.io/qr"
 )
 
+// configFetchTimeout is the timeout for fetching a remote config.
+const configFetchTimeout = 10 * time.Second
+
+// DevelopOptions are options for development mode set from the command
+// line.
+type DevelopOptions struct {
+	Trace             bool          // log each event loop transition
+	LoadRetries       int           // config load retries before waiting for a fix
+	LoadRetryInterval time.Duration // initial interval between retries, doubled on each retry
+	ReloadCommand     string        // shell command run on file updates before reloading
+	WatchConfigOnly   bool          // only watch the config file, not the templates
+	ReloadDelay       time.Duration // wait before restarting a stopped server, to release its port
+	Events            io.Writer     // JSON-lines event stream of state changes, if set; moves the access log to stderr
+}
+
+// ValidateOptions are options for the validate command set from the
+// command line.
+type ValidateOptions struct {
+	AllErrors bool // report all page and zone problems
+	JSON      bool // report the result as JSON
+	Strict    bool // report warnings, such as small zones, as errors
+
+	CheckReachability bool   // report pages not reachable from the entry page
+	Entry             string // entry page url for CheckReachability, if set
+}
+
+// InitOptions are options for the init command set from the command
+// line.
+type InitOptions struct {
+	JSON bool // report the files written as JSON
+}
+
 // App is the main "plug point" for the application, making the three
 // modes of "Serve" (embedded, on disk and development mode) and
 // "WriteAssets" injectable into the cli flags package. If the
@@ -20,8 +62,9 @@ import (
 type App struct {
 	interactive bool
 	serveFunc   func(*server) error
-	writeFunc   func(cfg *config, directory string) error
+	writeFunc   func(cfg *config, directory string) ([]string, error)
 	stopper     chan struct{} // for tests
+	stdin       io.Reader     // for reading tar streams, overridden in tests
 }
 
 // NewApp returns a new App.
@@ -29,6 +72,7 @@ func NewApp() *App {
 	return &App{
 		serveFunc: Serve,
 		writeFunc: WriteAssets,
+		stdin:     os.Stdin,
 	}
 }
 
@@ -37,128 +81,666 @@ func (a *App) Interactive() {
 	a.interactive = !a.interactive
 }
 
-// Serve serves the service from disk.
-func (a *App) Serve(address, port, configFile string) error {
-	configBytes, err := os.ReadFile(configFile)
+// isRemoteConfig reports if configFile is an http(s) url.
+func isRemoteConfig(configFile string) bool {
+	return strings.HasPrefix(configFile, "http://") || strings.HasPrefix(configFile, "https://")
+}
+
+// readConfig reads the config file from disk or, if it is an http(s)
+// url, fetches it. Only the config document may be remote; assets are
+// always local or embedded.
+func readConfig(configFile string) ([]byte, error) {
+	if !isRemoteConfig(configFile) {
+		return os.ReadFile(configFile)
+	}
+	client := &http.Client{Timeout: configFetchTimeout}
+	resp, err := client.Get(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("config fetch error: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("config fetch error: %s returned %s", configFile, resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("config fetch read error: %w", err)
+	}
+	return b, nil
+}
+
+// configDir returns the directory against which the relative paths in
+// configFile are resolved, or "" for remote configs, which are resolved
+// against the working directory.
+func configDir(configFile string) string {
+	if isRemoteConfig(configFile) {
+		return ""
+	}
+	return filepath.Dir(configFile)
+}
+
+// Serve serves the service from disk. The config file may be an
+// http(s) url. The config is re-read and validated on SIGHUP, the
+// current config being kept if it is invalid.
+func (a *App) Serve(address, port, configFile string, options ServerOptions) error {
+	config, err := loadServeConfig(configFile, options)
+	if err != nil {
+		return err
+	}
+
+	server, err := newServer(address, port, config, options)
+	if err != nil {
+		return err
+	}
+	if port, err = bindForQR(server, port, options); err != nil {
+		return err
+	}
+	if a.interactive && !options.Quiet {
+		if options.TLSAuto {
+			fmt.Printf("Running server on %s:%s and %s:%s\n", address, tlsAutoPort, address, tlsAutoHTTPPort)
+			fmt.Printf("(the index is at <https://%s/index>)\n", options.Domains[0])
+			printQR(fmt.Sprintf("https://%s%s", options.Domains[0], qrPath(config)), options)
+		} else {
+			fmt.Printf("Running server on %s:%s\n", address, port)
+			fmt.Printf("(the index is at <http://%s:%s/index>)\n", address, port)
+			printQR(qrURL(address, port, qrPath(config)), options)
+		}
+	}
+	stop := reloadOnHangup(server, configFile)
+	defer stop()
+	return a.serveFunc(server)
+}
+
+// loadServeConfig reads and validates configFile for serving, merging
+// the options.Overlay file, if set, over it.
+func loadServeConfig(configFile string, options ServerOptions) (*config, error) {
+	configBytes, err := readConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	opts := configOptions{strict: options.Strict, strictTemplates: options.StrictTemplates, assetsDir: options.AssetsDir}
+	if options.Overlay != "" {
+		if opts.overlay, err = os.ReadFile(options.Overlay); err != nil {
+			return nil, fmt.Errorf("config overlay error: %w", err)
+		}
+	}
+	return newConfigDir(configBytes, configDir(configFile), opts)
+}
+
+// ServeTar serves the service entirely from memory, reading the project
+// (config file configName and its assets) from the tar file tarFile, or
+// from stdin if tarFile is "-".
+func (a *App) ServeTar(address, port, tarFile, configName string, options ServerOptions) error {
+	var r io.Reader = a.stdin
+	if tarFile != "-" {
+		f, err := os.Open(tarFile)
+		if err != nil {
+			return fmt.Errorf("tar file error: %w", err)
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		r = f
+	}
+	tarFS, err := readTarFS(r)
+	if err != nil {
+		return err
+	}
+	configBytes, projectFS, err := tarProject(tarFS, configName)
 	if err != nil {
 		return err
 	}
 
-	config, err := newConfig(configBytes, false)
+	config, err := newConfigFS(configBytes, projectFS, configOptions{strict: options.Strict, strictTemplates: options.StrictTemplates})
 	if err != nil {
 		return err
 	}
 
-	server, err := newServer(address, port, config)
+	server, err := newServer(address, port, config, options)
 	if err != nil {
 		return err
 	}
-	if a.interactive {
-		fmt.Printf("Running server on %s:%s\n", address, port)
+	if port, err = bindForQR(server, port, options); err != nil {
+		return err
+	}
+	if a.interactive && !options.Quiet {
+		fmt.Printf("Running server from tar stream on %s:%s\n", address, port)
 		fmt.Printf("(the index is at <http://%s:%s/index>)\n", address, port)
+		printQR(qrURL(address, port, qrPath(config)), options)
 	}
 	return a.serveFunc(server)
 }
 
-// Demo serves the service from embedded assets.
-func (a *App) Demo(address, port string) error {
-	config, err := newConfig(configYaml, true) // is bytes
+// Demo serves the service from embedded assets, either the default
+// demo or, if example is set, the named embedded example.
+func (a *App) Demo(address, port, example string, options ServerOptions) error {
+	var config *config
+	var err error
+	if example == "" {
+		config, err = newConfig(configYaml, true) // is bytes
+	} else {
+		config, err = newExampleConfig(example)
+	}
 	if err != nil {
 		return err
 	}
 
-	server, err := newServer(address, port, config)
+	server, err := newServer(address, port, config, options)
 	if err != nil {
 		return err
 	}
-	if a.interactive {
-		fmt.Printf("Running demo server on %s:%s\n", address, port)
-		fmt.Printf("(the index is at <http://%s:%s/index>)\n", address, port)
+	// Optionally find a free port, so the port printed is the one used.
+	if options.PortScan && !options.TLSAuto {
+		if err := server.bindScan(); err != nil {
+			return err
+		}
+		port = server.serverPort
+	}
+	if port, err = bindForQR(server, port, options); err != nil {
+		return err
+	}
+	if a.interactive && !options.Quiet {
+		if options.TLSAuto {
+			fmt.Printf("Running demo server on %s:%s and %s:%s\n", address, tlsAutoPort, address, tlsAutoHTTPPort)
+			fmt.Printf("(the index is at <https://%s/index>)\n", options.Domains[0])
+			printQR(fmt.Sprintf("https://%s%s", options.Domains[0], qrPath(config)), options)
+		} else {
+			fmt.Printf("Running demo server on %s:%s\n", address, port)
+			fmt.Printf("(the index is at <http://%s:%s/index>)\n", address, port)
+			printQR(qrURL(address, port, qrPath(config)), options)
+		}
 	}
 	return a.serveFunc(server)
 }
 
-// Init writes the internal directories and config to disk.
-func (a *App) Init(dir string) error {
-	config, err := newConfig(configYaml, true) // is bytes
+// bindForQR binds the server's listener if a QR code is to be printed
+// for port 0, returning the port bound so that the code is of the url
+// actually served. Otherwise port is returned unchanged.
+func bindForQR(s *server, port string, options ServerOptions) (string, error) {
+	if !options.QR || options.TLSAuto || port != "0" || s.listener != nil {
+		return port, nil
+	}
+	if err := s.bindScan(); err != nil {
+		return "", err
+	}
+	return s.serverPort, nil
+}
+
+// qrPath returns the path of the url printed as a QR code: the entry
+// url of cfg if set, otherwise the index.
+func qrPath(cfg *config) string {
+	if cfg.EntryURL != "" {
+		return cfg.EntryURL
+	}
+	return "/index"
+}
+
+// qrURL returns the url of path for a server on address and port. An
+// unspecified address, such as 0.0.0.0, is replaced with the first
+// non-loopback address of the host, so that the url works from a phone
+// on the same network.
+func qrURL(address, port, path string) string {
+	if ip := net.ParseIP(address); ip != nil && ip.IsUnspecified() {
+		if addrs, err := net.InterfaceAddrs(); err == nil {
+			for _, a := range addrs {
+				if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() && n.IP.To4() != nil {
+					address = n.IP.String()
+					break
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(address, port), path)
+}
+
+// printQR prints a QR code of url if the QR option is set.
+func printQR(url string, options ServerOptions) {
+	if !options.QR {
+		return
+	}
+	code, err := qr.Encode(url, qr.L)
+	if err != nil {
+		fmt.Printf("qr code error: %v\n", err)
+		return
+	}
+	fmt.Print(qrString(code))
+}
+
+// Validate validates the config file on disk. If options.AllErrors is
+// set all page and zone problems are reported, otherwise only the
+// first. If options.JSON is set the result is written to stdout as
+// JSON, including the code of each problem.
+func (a *App) Validate(configFile string, options ValidateOptions) error {
+	configBytes, err := readConfig(configFile)
+	if err == nil {
+		_, err = newConfigDir(configBytes, configDir(configFile), configOptions{
+			allErrors:         options.AllErrors,
+			strict:            options.Strict,
+			checkReachability: options.CheckReachability,
+			entryURL:          options.Entry,
+		})
+	}
+	if options.JSON {
+		if werr := writeValidation(os.Stdout, configFile, err); werr != nil {
+			return werr
+		}
+		if err != nil {
+			return errValidationReported
+		}
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 	if a.interactive {
+		fmt.Printf("config %q ok\n", configFile)
+	}
+	return nil
+}
+
+// Sitemap writes a JSON description of the site structure described by
+// the config file to stdout.
+func (a *App) Sitemap(configFile string) error {
+	configBytes, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	config, err := newConfigDir(configBytes, configDir(configFile), configOptions{})
+	if err != nil {
+		return err
+	}
+	return writeSitemap(os.Stdout, config.Pages)
+}
+
+// AddPage appends a page without zones, described by options, to the
+// config file on disk, keeping the formatting of the rest of the file.
+// Unless options.SkipValidate is set the resulting config is validated
+// first, ignoring pages without zones, and is only written if valid.
+func (a *App) AddPage(configFile string, options AddPageOptions) error {
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return err
+	}
+	configBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	out, err := addPageYAML(configBytes, options)
+	if err != nil {
+		return err
+	}
+	if !options.SkipValidate {
+		c, err := newConfigDir(out, configDir(configFile), configOptions{allErrors: true})
+		if err := withoutNoZones(err); err != nil {
+			return err
+		}
+		// Image pages are not checked by the config validation, but
+		// the image of a page being added is expected to exist.
+		if _, err := fs.Stat(c.AssetsFS, options.ImagePath); err != nil {
+			return fmt.Errorf("image %q not found", options.ImagePath)
+		}
+	}
+	if err := os.WriteFile(configFile, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("config write error: %w", err)
+	}
+	if a.interactive {
+		fmt.Printf("page %s added to %q\n", options.URL, configFile)
+	}
+	return nil
+}
+
+// Thumbnails writes thumbnails of the page images described by the
+// config file to the thumbs directory of its images directory,
+// skipping those which are up to date.
+func (a *App) Thumbnails(configFile string, options ThumbnailOptions) error {
+	configBytes, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+	config, err := newConfigDir(configBytes, configDir(configFile), configOptions{})
+	if err != nil {
+		return err
+	}
+	written, err := writeThumbnails(config, config.resolvePath(config.AssetsDir), options)
+	if a.interactive {
+		for _, w := range written {
+			fmt.Printf("thumbnail %q written\n", w)
+		}
+		if err == nil && len(written) == 0 {
+			fmt.Println("thumbnails up to date")
+		}
+	}
+	return err
+}
+
+// PDF writes a storyboard of the pages described by the config file,
+// with their zones and targets drawn, to the PDF file out.
+func (a *App) PDF(configFile, out string) error {
+	configBytes, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+	config, err := newConfigDir(configBytes, configDir(configFile), configOptions{})
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("pdf create error: %w", err)
+	}
+	err = writeStoryboard(config, newPDFWriter(f))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if a.interactive {
+		fmt.Printf("%d pages written to %q\n", len(config.Pages), out)
+	}
+	return nil
+}
+
+// Export writes the pages described by the config file as static html
+// files, with the images and static files, to the directory out.
+func (a *App) Export(configFile, out string) error {
+	configBytes, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+	config, err := newConfigDir(configBytes, configDir(configFile), configOptions{})
+	if err != nil {
+		return err
+	}
+	s, err := newServer("127.0.0.1", "0", config, ServerOptions{})
+	if err != nil {
+		return err
+	}
+	written, err := exportSite(s, out)
+	if err != nil {
+		return err
+	}
+	if a.interactive {
+		fmt.Printf("%d files written to %q\n", len(written), out)
+	}
+	return nil
+}
+
+// Analyze writes a table of the visits to each page described by the
+// config file, counted from the combined format access log logFile, to
+// stdout.
+func (a *App) Analyze(configFile, logFile string) error {
+	configBytes, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+
+	config, err := newConfigDir(configBytes, configDir(configFile), configOptions{})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(logFile)
+	if err != nil {
+		return fmt.Errorf("access log error: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	visits, err := countVisits(config, f)
+	if err != nil {
+		return err
+	}
+	return writeVisits(os.Stdout, visits)
+}
+
+// Init writes the internal directories and config to disk. If
+// options.JSON is set the files written are reported to stdout as JSON,
+// otherwise in interactive mode each file written is printed, followed
+// by a count.
+func (a *App) Init(dir string, options InitOptions) error {
+	config, err := newConfig(configYaml, true) // is bytes
+	if err != nil {
+		return err
+	}
+	if a.interactive && !options.JSON {
 		fmt.Printf("writing demo files to %q\n", dir)
 	}
-	return a.writeFunc(config, dir)
+	written, err := a.writeFunc(config, dir)
+	if err != nil {
+		return err
+	}
+	if options.JSON {
+		return writeInitReport(os.Stdout, dir, written)
+	}
+	if a.interactive {
+		for _, f := range written {
+			fmt.Printf("  wrote %s\n", f)
+		}
+		fmt.Printf("wrote %d files\n", len(written))
+	}
+	return nil
+}
+
+// initReport is the JSON report of the files written by Init.
+type initReport struct {
+	Directory string   `json:"directory"`
+	Files     []string `json:"files"`
+}
+
+// writeInitReport writes the JSON report of the files written to
+// directory to w.
+func writeInitReport(w io.Writer, directory string, files []string) error {
+	if files == nil {
+		files = []string{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(initReport{Directory: directory, Files: files})
+}
+
+// retryWithBackoff calls fn until it succeeds, retrying up to retries
+// times after waiting interval, which is doubled after each retry. The
+// last error is returned if all attempts fail or ctx is done.
+func retryWithBackoff(ctx context.Context, retries int, interval time.Duration, fn func() error) error {
+	err := fn()
+	for i := 0; err != nil && i < retries; i++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
+		interval *= 2
+		err = fn()
+	}
+	return err
+}
+
+// runReloadCommand runs the shell command command, returning an error
+// including its stderr output if it fails.
+func runReloadCommand(ctx context.Context, command string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("reload command %q error: %w\n%s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// watchDescriptors returns the descriptors of the files watched in
+// development mode: the config file and, unless configOnly is set, the
+// templates in templateDir matching templateSuffixes.
+func watchDescriptors(configFile, templateDir string, templateSuffixes []string, configOnly bool) []DirFilesDescriptor {
+	descriptors := []DirFilesDescriptor{
+		DirFilesDescriptor{filepath.Dir(configFile), []string{filepath.Ext(configFile)}},
+	}
+	if !configOnly {
+		descriptors = append(descriptors, DirFilesDescriptor{templateDir, templateSuffixes})
+	}
+	return descriptors
 }
 
 // ServeInDevelopment serves the service from disk in development mode,
 // using an extraordinarily elaborate event loop and filesystem watcher
-// to reload the configuration and server on changes, waiting for
-// further file writes when an error occurs.
-func (a *App) ServeInDevelopment(address, port string, templateSuffixes []string, configFile string) error {
+// to reload the configuration and swap the server handler on changes,
+// waiting for further file writes when an error occurs. The last good
+// handler is kept serving while waiting, with an error banner overlaid
+// on its pages.
+func (a *App) ServeInDevelopment(address, port string, templateSuffixes []string, configFile string, options ServerOptions, devOptions DevelopOptions) error {
 
 	var srv *server
 	var cfg *config
 	var templateDir = "assets/templates"
+	var serverStopped bool // a previous server stopped unexpectedly
+
+	// overlay is shared by successive servers to report errors.
+	overlay := &devOverlay{}
+	options.devOverlay = overlay
+
+	// events reports state changes to tooling, if configured. The
+	// access and session logs are then written to stderr, so that they
+	// are not interleaved with the events on stdout.
+	events := newDevEvents(devOptions.Events)
+	if devOptions.Events != nil {
+		options.logWriter = os.Stderr
+	}
+
+	// serveErrs reports a server stopping unexpectedly to the file
+	// watcher, which waits for a file fix to start a new server.
+	serveErrs := make(chan error, 1)
 
 	// 1. Define the sets of commands for the event loop.
 
 	// loadConfigCmd is a configuration loader command.
+	// Reading and validating are retried to smooth over config files
+	// that are written non-atomically by external tools.
 	loadConfigCmd := func(ctx context.Context) Msg {
-		configBytes, err := os.ReadFile(configFile)
-		if err != nil {
+		var config *config
+		var fileErr bool
+		err := retryWithBackoff(ctx, devOptions.LoadRetries, devOptions.LoadRetryInterval, func() error {
+			configBytes, err := os.ReadFile(configFile)
+			if err != nil {
+				fileErr = true
+				return err
+			}
+			fileErr = false
+			config, err = newConfigDir(configBytes, configDir(configFile), configOptions{strict: options.Strict, strictTemplates: options.StrictTemplates})
+			return err
+		})
+		if err != nil && fileErr {
 			log.Printf("config file error: %v", err)
+			overlay.setError(err)
+			events.error(err)
 			return "FILE_WAIT"
 		}
-
-		config, err := newConfig(configBytes, false)
 		if err != nil {
 			log.Printf("config load error: %v", err)
 			log.Println("waiting for file fix")
+			overlay.setError(err)
+			events.error(err)
 			return "FILE_WAIT"
 		}
 		cfg = config
-		templateDir = filepath.Join(cfg.AssetsDir, "templates")
+		templateDir = cfg.resolvePath(filepath.Join(cfg.AssetsDir, cfg.Dirs.Templates))
+		if cfg.TemplatesDir != "" {
+			templateDir = cfg.resolvePath(cfg.TemplatesDir)
+		}
 		log.Println("config load ok")
+		events.emit("config_ok")
 		return "CONFIG_LOAD_OK"
 	}
 
-	// startServerCmd is a server starting command.
+	// startServerCmd is a server starting command. Once a server is
+	// running, its handler is swapped for one built from the reloaded
+	// config, fully validated with, for example, the page notes
+	// rendered afresh, rather than restarting the server. The running
+	// handler is kept if the new one cannot be built.
 	startServerCmd := func(ctx context.Context) Msg {
-		var err error
 		if srv != nil {
-			_ = srv.webServer.Shutdown(context.Background())
+			if err := srv.reload(cfg); err != nil {
+				log.Printf("server reload error: %v", err)
+				log.Println("waiting for file fix")
+				overlay.setError(err)
+				events.error(err)
+				return "FILE_WAIT"
+			}
+			overlay.clearError()
+			log.Println("server reloaded ok")
+			events.emit("server_reloaded")
+			return "SERVER_STARTED"
+		}
+		// Give the OS time to release the port of a stopped server.
+		if serverStopped && devOptions.ReloadDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return ""
+			case <-time.After(devOptions.ReloadDelay):
+			}
+		}
+		newSrv, err := newServer(address, port, cfg, options)
+		var handler http.Handler
+		if err == nil {
+			handler, err = newSrv.buildHandler()
 		}
-		srv, err = newServer(address, port, cfg)
 		if err != nil {
 			log.Printf("server start error: %v", err)
 			log.Println("waiting for file fix")
+			overlay.setError(err)
+			events.error(err)
 			return "FILE_WAIT"
 		}
-		log.Printf("Running server on %s:%s\n", address, port)
-		log.Printf("   (the index is at <http://%s:%s/index>)\n", address, port)
+		newSrv.handler.store(handler)
+		srv = newSrv
+		serverStopped = false
+		overlay.clearError()
+		if !options.Quiet {
+			log.Printf("Running server on %s:%s\n", address, port)
+			log.Printf("   (the index is at <http://%s:%s/index>)\n", address, port)
+		}
 
 		var wg sync.WaitGroup
 		wg.Go(func() {
 			// normally a blocking call
-			err := a.serveFunc(srv)
+			err := a.serveFunc(newSrv)
 			if err != nil && !errors.Is(err, http.ErrServerClosed) {
-				log.Fatalf("fatal server error: %v", err) // should not happen
+				select {
+				case serveErrs <- err:
+				default:
+				}
 			}
 		})
 		go func() {
 			wg.Wait()
 		}()
 		log.Println("server started ok")
+		events.emit("server_started")
 		return "SERVER_STARTED"
 	}
 
+	// reloadCmd runs the reload command, such as a build step
+	// compiling css to the static directory, before the config is
+	// reloaded.
+	reloadCmd := func(ctx context.Context) Msg {
+		if err := runReloadCommand(ctx, devOptions.ReloadCommand); err != nil {
+			log.Print(err)
+			log.Println("waiting for file fix")
+			overlay.setError(err)
+			events.error(err)
+			return "FILE_WAIT"
+		}
+		log.Println("reload command ok")
+		events.emit("reload_command_ok")
+		return "RELOAD_OK"
+	}
+
 	// fileWaitForUpdateCmd is a file watcher command.
 	fileWaitForUpdateCmd := func(ctx context.Context) Msg {
 		fcn, err := NewFileChangeNotifier(
-			[]DirFilesDescriptor{
-				DirFilesDescriptor{filepath.Dir(configFile), []string{filepath.Ext(configFile)}},
-				DirFilesDescriptor{templateDir, templateSuffixes},
-			},
+			watchDescriptors(configFile, templateDir, templateSuffixes, devOptions.WatchConfigOnly),
 		)
 		if err != nil {
 			log.Fatalf("error initialising watcher: %v", err)
@@ -174,12 +756,21 @@ func (a *App) ServeInDevelopment(address, port string, templateSuffixes []string
 			log.Printf("file watch error: %v", err)
 			log.Println("waiting for file fix")
 			return "FILE_WAIT"
-		case _, ok := <-fcn.Update():
+		case err := <-serveErrs:
+			log.Printf("server error: %v", err)
+			log.Println("waiting for file fix")
+			overlay.setError(err)
+			events.error(err)
+			srv = nil
+			serverStopped = true
+			return "FILE_WAIT"
+		case files, ok := <-fcn.Update():
 			if !ok {
 				return ""
 			}
 			log.Println("---------------------------------------")
 			log.Println("file update detected")
+			events.emit("reload", files...)
 		}
 		return "FILE_UPDATED"
 	}
@@ -188,20 +779,35 @@ func (a *App) ServeInDevelopment(address, port string, templateSuffixes []string
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 3. initiaise and run the event loop.
+	// 3. initiaise and run the event loop. If a reload command is set
+	// it is run on file updates before the config is reloaded.
+	labelledCmds := []LabelledCmd{
+		LabelledCmd{"CONFIG_LOAD_OK", startServerCmd},
+		LabelledCmd{"CONFIG_LOAD_FAILED", fileWaitForUpdateCmd},
+		LabelledCmd{"FILE_UPDATED", loadConfigCmd},
+		LabelledCmd{"SERVER_STARTED", fileWaitForUpdateCmd},
+	}
+	if devOptions.ReloadCommand != "" {
+		labelledCmds[2] = LabelledCmd{"FILE_UPDATED", reloadCmd}
+		labelledCmds = append(labelledCmds, LabelledCmd{"RELOAD_OK", loadConfigCmd})
+	}
 	el, err := NewEventLoop(
-		[]LabelledCmd{
-			LabelledCmd{"CONFIG_LOAD_OK", startServerCmd},
-			LabelledCmd{"CONFIG_LOAD_FAILED", fileWaitForUpdateCmd},
-			LabelledCmd{"FILE_UPDATED", loadConfigCmd},
-			LabelledCmd{"SERVER_STARTED", fileWaitForUpdateCmd},
-		},
+		labelledCmds,
 		loadConfigCmd,        // start command
 		fileWaitForUpdateCmd, // default command
 	)
 	if err != nil {
 		log.Fatalf("event loop init error: %v", err)
 	}
+	if devOptions.Trace {
+		el.NameCmd("loadConfig", loadConfigCmd)
+		el.NameCmd("startServer", startServerCmd)
+		el.NameCmd("fileWaitForUpdate", fileWaitForUpdateCmd)
+		el.NameCmd("reload", reloadCmd)
+		el.SetTrace(func(state string, msg Msg, next string) {
+			log.Printf("[%s] %s -> %s", state, msg, next)
+		})
+	}
 
 	// app.stopper is for stopping the server in tests.
 	if a.stopper != nil {
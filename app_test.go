@@ -1,12 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 const testFilePattern = "firstgo_apptest_*"
@@ -14,7 +22,9 @@ const testFilePattern = "firstgo_apptest_*"
 // writeConfig is a file writer helper.
 func writeConfig(t *testing.T, config []byte) string {
 	t.Helper()
-	tf, err := os.CreateTemp("", testFilePattern)
+	// the config is written alongside the assets directory, against
+	// which its relative paths are resolved
+	tf, err := os.CreateTemp(".", testFilePattern)
 	if err != nil {
 		t.Fatal("create temp error", err)
 	}
@@ -121,6 +131,8 @@ func TestApp(t *testing.T) {
 		address     string
 		app         App
 		mkConfig    func(t *testing.T, asPath bool) string
+		devOptions  DevelopOptions
+		initOptions InitOptions
 		errContains string
 	}{
 		{
@@ -245,7 +257,7 @@ func TestApp(t *testing.T) {
 			mode: "init",
 			app: App{
 				interactive: true,
-				writeFunc:   func(cfg *config, directory string) error { return nil },
+				writeFunc:   func(cfg *config, directory string) ([]string, error) { return []string{"config.yaml"}, nil },
 			},
 			mkConfig: makeOKConfig,
 		},
@@ -254,17 +266,27 @@ func TestApp(t *testing.T) {
 			mode: "init",
 			app: App{
 				interactive: false,
-				writeFunc:   func(cfg *config, directory string) error { return nil },
+				writeFunc:   func(cfg *config, directory string) ([]string, error) { return []string{"config.yaml"}, nil },
 			},
 			mkConfig: makeOKConfig,
 			address:  "127.0.0.1",
 		},
+		{
+			name: "init ok json",
+			mode: "init",
+			app: App{
+				interactive: true,
+				writeFunc:   func(cfg *config, directory string) ([]string, error) { return []string{"config.yaml"}, nil },
+			},
+			mkConfig:    makeOKConfig,
+			initOptions: InitOptions{JSON: true},
+		},
 		{
 			name: "init failure",
 			mode: "init",
 			app: App{
 				interactive: false,
-				writeFunc:   func(cfg *config, directory string) error { return errors.New("init failure") },
+				writeFunc:   func(cfg *config, directory string) ([]string, error) { return nil, errors.New("init failure") },
 			},
 			mkConfig:    makeOKConfig,
 			errContains: "init failure",
@@ -274,11 +296,24 @@ func TestApp(t *testing.T) {
 			mode: "init",
 			app: App{
 				interactive: false,
-				writeFunc:   func(cfg *config, directory string) error { return nil },
+				writeFunc:   func(cfg *config, directory string) ([]string, error) { return []string{"config.yaml"}, nil },
 			},
 			mkConfig:    makeNotOKConfig,
 			errContains: "invalid Zone Target URL",
 		},
+		{
+			name:     "validate ok",
+			mode:     "validate",
+			app:      App{interactive: true},
+			mkConfig: makeOKConfig,
+		},
+		{
+			name:        "validate all fail",
+			mode:        "validate",
+			app:         App{interactive: false},
+			mkConfig:    makeNotOKConfig,
+			errContains: "invalid Zone Target URL",
+		},
 		{
 			name:    "development server ok",
 			mode:    "development",
@@ -287,7 +322,41 @@ func TestApp(t *testing.T) {
 				interactive: true,
 				serveFunc:   func(*server) error { return nil },
 			},
-			mkConfig: makeOKConfig,
+			mkConfig:   makeOKConfig,
+			devOptions: DevelopOptions{Trace: true},
+		},
+		{
+			name:    "development server serve failure",
+			mode:    "development",
+			address: "127.0.0.1",
+			app: App{
+				interactive: true,
+				serveFunc:   func(*server) error { return errors.New("serve fail") },
+			},
+			mkConfig:   makeOKConfig,
+			devOptions: DevelopOptions{Trace: true},
+		},
+		{
+			name:    "development server reload command ok",
+			mode:    "development",
+			address: "127.0.0.1",
+			app: App{
+				interactive: true,
+				serveFunc:   func(*server) error { return nil },
+			},
+			mkConfig:   makeOKConfig,
+			devOptions: DevelopOptions{Trace: true, ReloadCommand: "true"},
+		},
+		{
+			name:    "development server events",
+			mode:    "development",
+			address: "127.0.0.1",
+			app: App{
+				interactive: true,
+				serveFunc:   func(*server) error { return nil },
+			},
+			mkConfig:   makeOKConfig,
+			devOptions: DevelopOptions{Events: &bytes.Buffer{}},
 		},
 	}
 
@@ -301,19 +370,26 @@ func TestApp(t *testing.T) {
 				}
 				config := tt.mkConfig(t, true) // bool is for "asPath" mode
 				t.Cleanup(cleanup(config))
-				err = tt.app.Serve(tt.address, "8000", config)
+				err = tt.app.Serve(tt.address, "8000", config, ServerOptions{})
 			case "demo":
 				config := tt.mkConfig(t, false) // config as string only
 				orig := configYaml
 				configYaml = []byte(config) // override embed
-				err = tt.app.Demo(tt.address, "8000")
+				err = tt.app.Demo(tt.address, "8000", "", ServerOptions{})
 				configYaml = orig
 			case "init":
 				config := tt.mkConfig(t, false) // config as string only
 				orig := configYaml
 				configYaml = []byte(config) // override embed
-				err = tt.app.Init("anything goes")
+				err = tt.app.Init("anything goes", tt.initOptions)
 				configYaml = orig
+			case "validate":
+				cleanup := func(fileName string) func() {
+					return func() { _ = os.Remove(fileName) }
+				}
+				config := tt.mkConfig(t, true) // bool is for "asPath" mode
+				t.Cleanup(cleanup(config))
+				err = tt.app.Validate(config, ValidateOptions{AllErrors: true})
 			case "development":
 				cleanup := func(fileName string) func() {
 					return func() { _ = os.Remove(fileName) }
@@ -327,7 +403,7 @@ func TestApp(t *testing.T) {
 					fmt.Println("stopper fired")
 					tt.app.stopper <- struct{}{}
 				}()
-				err = tt.app.ServeInDevelopment(tt.address, "8000", []string{"html"}, config)
+				err = tt.app.ServeInDevelopment(tt.address, "8000", []string{"html"}, config, ServerOptions{}, tt.devOptions)
 			default:
 				t.Fatalf("mode %q not known", tt.mode)
 			}
@@ -346,6 +422,21 @@ func TestApp(t *testing.T) {
 	}
 }
 
+func TestWriteInitReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeInitReport(&buf, "demo", []string{"demo/assets/static/styles.css", "demo/config.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	var got initReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := initReport{Directory: "demo", Files: []string{"demo/assets/static/styles.css", "demo/config.yaml"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("report got - want +: %v", diff)
+	}
+}
+
 func TestAppNewInteractive(t *testing.T) {
 	app := NewApp()
 	if app.interactive != false {
@@ -356,3 +447,217 @@ func TestAppNewInteractive(t *testing.T) {
 		t.Fatal("expected interactive field to be true")
 	}
 }
+
+func TestAppServeRemoteConfig(t *testing.T) {
+	config := makeOKConfig(t, false)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/config.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(config))
+	}))
+	defer ts.Close()
+
+	app := App{serveFunc: func(*server) error { return nil }}
+
+	if err := app.Serve("127.0.0.1", "8000", ts.URL+"/config.yaml", ServerOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := app.Serve("127.0.0.1", "8000", ts.URL+"/missing.yaml", ServerOptions{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if got, want := err.Error(), "404 Not Found"; !strings.Contains(got, want) {
+		t.Errorf("got err %q want err with %q", got, want)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	errNotYet := errors.New("not yet")
+
+	tests := []struct {
+		name      string
+		retries   int
+		succeedOn int // attempt on which fn succeeds, 0 for never
+		wantCalls int
+		wantErr   bool
+	}{
+		{"first attempt", 3, 1, 1, false},
+		{"after retries", 3, 3, 3, false},
+		{"retries exhausted", 2, 0, 3, true},
+		{"no retries", 0, 2, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryWithBackoff(context.Background(), tt.retries, time.Millisecond, func() error {
+				calls++
+				if calls == tt.succeedOn {
+					return nil
+				}
+				return errNotYet
+			})
+			if got, want := err != nil, tt.wantErr; got != want {
+				t.Errorf("error got %v want error %t", err, want)
+			}
+			if got, want := calls, tt.wantCalls; got != want {
+				t.Errorf("calls got %d want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestRetryWithBackoffCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := retryWithBackoff(ctx, 3, time.Hour, func() error {
+		calls++
+		return errors.New("fail")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("calls got %d want %d", got, want)
+	}
+}
+
+func TestWatchDescriptors(t *testing.T) {
+	suffixes := []string{"html"}
+	config := DirFilesDescriptor{"config", []string{".yaml"}}
+	templates := DirFilesDescriptor{"assets/templates", suffixes}
+
+	got := watchDescriptors("config/config.yaml", "assets/templates", suffixes, false)
+	if diff := cmp.Diff([]DirFilesDescriptor{config, templates}, got); diff != "" {
+		t.Errorf("descriptors mismatch (-want +got):\n%s", diff)
+	}
+	got = watchDescriptors("config/config.yaml", "assets/templates", suffixes, true)
+	if diff := cmp.Diff([]DirFilesDescriptor{config}, got); diff != "" {
+		t.Errorf("config only descriptors mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestBindForQR checks that port 0 is bound and resolved when a QR code
+// is to be printed, and otherwise left to the server.
+func TestBindForQR(t *testing.T) {
+	s, err := newServer("127.0.0.1", "0", initServerConfig(t), ServerOptions{QR: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := bindForQR(s, "0", s.options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := s.listener
+	defer func() {
+		_ = ln.Close()
+	}()
+	if port == "0" || port != s.serverPort {
+		t.Errorf("got port %q, server port %q", port, s.serverPort)
+	}
+	if got, want := qrURL("127.0.0.1", port, "/index"), "http://127.0.0.1:"+port+"/index"; got != want {
+		t.Errorf("got url %q want %q", got, want)
+	}
+
+	s, err = newServer("127.0.0.1", "0", initServerConfig(t), ServerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port, err = bindForQR(s, "0", s.options); err != nil || port != "0" || s.listener != nil {
+		t.Errorf("without qr got port %q listener %v error %v", port, s.listener, err)
+	}
+}
+
+func TestRunReloadCommand(t *testing.T) {
+	if err := runReloadCommand(context.Background(), "true"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := runReloadCommand(context.Background(), "echo build failed >&2; exit 2")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"exit status 2", "build failed"} {
+		if got := err.Error(); !strings.Contains(got, want) {
+			t.Errorf("got err %q want err with %q", got, want)
+		}
+	}
+}
+
+// TestAppServeConfigDir checks that relative asset paths are resolved
+// against the directory of the config file, and absolute paths are
+// unchanged.
+func TestAppServeConfigDir(t *testing.T) {
+	assets, err := filepath.Abs("assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(assets, filepath.Join(dir, "project-assets")); err != nil {
+		t.Fatal(err)
+	}
+	config := makeOKConfig(t, false)
+
+	app := App{serveFunc: func(*server) error { return nil }}
+	for _, assetsDir := range []string{"project-assets", assets} {
+		configFile := filepath.Join(dir, "config.yaml")
+		b := strings.Replace(config, `assetsDir: "assets"`, fmt.Sprintf("assetsDir: %q", assetsDir), 1)
+		if err := os.WriteFile(configFile, []byte(b), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.Serve("127.0.0.1", "8000", configFile, ServerOptions{}); err != nil {
+			t.Errorf("assetsDir %q unexpected error: %v", assetsDir, err)
+		}
+		if err := app.Validate(configFile, ValidateOptions{}); err != nil {
+			t.Errorf("assetsDir %q unexpected validate error: %v", assetsDir, err)
+		}
+	}
+}
+
+// TestLoadServeConfigAssetsDir checks that the AssetsDir option
+// replaces the config's assetsDir, relative to the config file.
+func TestLoadServeConfigAssetsDir(t *testing.T) {
+	assets, err := filepath.Abs("assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(assets, filepath.Join(dir, AssetDirName)); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, ConfigFileName)
+	b := strings.Replace(makeOKConfig(t, false), `assetsDir: "assets"`, `assetsDir: "elsewhere"`, 1)
+	if err := os.WriteFile(configFile, []byte(b), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadServeConfig(configFile, ServerOptions{}); err == nil {
+		t.Error("expected a missing assetsDir error")
+	}
+	c, err := loadServeConfig(configFile, ServerOptions{AssetsDir: AssetDirName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.AssetsDir, AssetDirName; got != want {
+		t.Errorf("assetsDir got %q want %q", got, want)
+	}
+}
+
+// TestLoadServeConfigStrictTemplates checks that the StrictTemplates
+// option reaches the config's templates.
+func TestLoadServeConfigStrictTemplates(t *testing.T) {
+	configFile := makeOKConfig(t, true)
+	defer func() { _ = os.Remove(configFile) }()
+
+	for _, strict := range []bool{false, true} {
+		c, err := loadServeConfig(configFile, ServerOptions{StrictTemplates: strict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.strictTemplates; got != strict {
+			t.Errorf("strict templates got %t want %t", got, strict)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// zones.js wires up the optional right and middle mouse button targets
+// of clickable zones, set in the data-right-target and
+// data-middle-target attributes, "back" zones, set with data-back,
+// which return to the previous page in the browser history, and overlay
+// zones, set with data-overlay. Clicking an overlay zone toggles the
+// "open" class of the element with the overlay name as its id, if any,
+// and dispatches a "zone-overlay" event for the project's scripts.
+// If the page is on the config's tour, set in the data-tour-prev and
+// data-tour-next attributes of the body, the left and right arrow keys
+// move to the previous and next tour pages. A page with an auto
+// advance, set in the data-auto-advance and data-auto-advance-after
+// attributes of the body, moves to its target after that many
+// milliseconds.
+document.querySelectorAll(".clickable-zone").forEach(function (zone) {
+    if (zone.dataset.overlay) {
+        zone.addEventListener("click", function (e) {
+            e.preventDefault();
+            var overlay = document.getElementById(zone.dataset.overlay);
+            if (overlay) {
+                overlay.classList.toggle("open");
+            }
+            document.dispatchEvent(new CustomEvent("zone-overlay", {
+                detail: { name: zone.dataset.overlay, element: overlay }
+            }));
+        });
+    }
+    if (zone.dataset.back) {
+        zone.addEventListener("click", function (e) {
+            e.preventDefault();
+            history.back();
+        });
+    }
+    if (zone.dataset.rightTarget) {
+        zone.addEventListener("contextmenu", function (e) {
+            e.preventDefault();
+            window.location.href = zone.dataset.rightTarget;
+        });
+    }
+    if (zone.dataset.middleTarget) {
+        zone.addEventListener("auxclick", function (e) {
+            if (e.button !== 1) {
+                return;
+            }
+            e.preventDefault();
+            window.location.href = zone.dataset.middleTarget;
+        });
+    }
+});
+
+document.addEventListener("keydown", function (e) {
+    if (e.altKey || e.ctrlKey || e.metaKey || e.shiftKey) {
+        return;
+    }
+    var target = "";
+    if (e.key === "ArrowLeft") {
+        target = document.body.dataset.tourPrev;
+    } else if (e.key === "ArrowRight") {
+        target = document.body.dataset.tourNext;
+    }
+    if (target) {
+        e.preventDefault();
+        window.location.href = target;
+    }
+});
+
+if (document.body.dataset.autoAdvance) {
+    window.setTimeout(function () {
+        window.location.href = document.body.dataset.autoAdvance;
+    }, parseInt(document.body.dataset.autoAdvanceAfter, 10));
+}
@@ -6,12 +6,25 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
 
+// defaultMaxBodyBytes is the default request body limit.
+const defaultMaxBodyBytes = 4 << 20
+
+// defaultLoadRetries and defaultLoadRetryInterval are the default
+// develop mode config load retry settings.
+const (
+	defaultLoadRetries       = 3
+	defaultLoadRetryInterval = 100 * time.Millisecond
+)
+
 const (
 	ShortUsage      = "A web server for prototyping web interfaces from sketches"
 	LongDescription = `The firstgo server uses a configuration yaml file with templates in
@@ -22,10 +35,128 @@ const (
 // Applicator is an interface to the central coordinator for the project
 // (concretely provided by App in app.go) to allow for testing.
 type Applicator interface {
-	Serve(address, port, configFile string) error
-	Init(directory string) error
-	Demo(address, port string) error
-	ServeInDevelopment(address, port string, templateSuffixes []string, configFile string) error
+	Serve(address, port, configFile string, options ServerOptions) error
+	ServeTar(address, port, tarFile, configName string, options ServerOptions) error
+	Init(directory string, options InitOptions) error
+	Validate(configFile string, options ValidateOptions) error
+	Sitemap(configFile string) error
+	Analyze(configFile, logFile string) error
+	AddPage(configFile string, options AddPageOptions) error
+	Thumbnails(configFile string, options ThumbnailOptions) error
+	PDF(configFile, out string) error
+	Export(configFile, out string) error
+	Demo(address, port, example string, options ServerOptions) error
+	ServeInDevelopment(address, port string, templateSuffixes []string, configFile string, options ServerOptions, devOptions DevelopOptions) error
+}
+
+// serverOptions collects the ServerOptions from the common server
+// flags.
+func serverOptions(c *cli.Command) ServerOptions {
+	return ServerOptions{
+		RateLimit:  c.Float64("rate-limit"),
+		TrustProxy: c.Bool("trust-proxy"),
+		Quiet:      c.Bool("quiet"),
+		H2C:        c.Bool("h2c"),
+
+		MaxBodyBytes: c.Int64("max-body-bytes"),
+		NoRecover:    c.Bool("no-recover"),
+
+		RequestTimeout: c.Duration("request-timeout"),
+		SlowThreshold:  c.Duration("slow-threshold"),
+		Pprof:          c.Bool("pprof"),
+		MaxConns:       c.Int("max-conns"),
+		Precompile:     c.Bool("precompile"),
+		ETag:           c.Bool("etag"),
+		ShowZones:      c.Bool("show-zones"),
+		Placeholders:   c.Bool("placeholder-images"),
+		Strict:         c.Bool("strict"),
+		Overlay:        c.String("overlay"),
+		IdleShutdown:   c.Duration("idle-shutdown"),
+
+		StrictTemplates: c.Bool("strict-templates"),
+
+		TLSAuto:      c.Bool("tls-auto"),
+		Domains:      c.StringSlice("domain"),
+		CertCacheDir: c.String("cert-cache"),
+		HSTS:         c.Bool("hsts"),
+		PortScan:     c.Bool("port-scan"),
+		QR:           c.Bool("qr"),
+		CachePages:   c.Bool("cache-pages"),
+		SessionParam: c.String("log-session-param"),
+		ServerHeader: serverHeader(c),
+	}
+}
+
+// serverHeader returns the server-header flag value, or nil if it is
+// not set, leaving the Server header unchanged.
+func serverHeader(c *cli.Command) *string {
+	if !c.IsSet("server-header") {
+		return nil
+	}
+	v := c.String("server-header")
+	return &v
+}
+
+// developOptions collects the DevelopOptions from the develop command
+// flags.
+func developOptions(c *cli.Command) DevelopOptions {
+	opts := DevelopOptions{
+		Trace:             c.Bool("trace"),
+		LoadRetries:       c.Int("load-retries"),
+		LoadRetryInterval: c.Duration("load-retry-interval"),
+		ReloadCommand:     c.String("reload-command"),
+		WatchConfigOnly:   c.Bool("watch-config-only"),
+		ReloadDelay:       c.Duration("reload-delay"),
+	}
+	if c.Bool("events") {
+		opts.Events = os.Stdout
+	}
+	return opts
+}
+
+// validateServerOptions validates the common server flags.
+func validateServerOptions(c *cli.Command) error {
+	if c.Float64("rate-limit") < 0 {
+		return fmt.Errorf("invalid rate limit: %v", c.Float64("rate-limit"))
+	}
+	if c.Int64("max-body-bytes") < 0 {
+		return fmt.Errorf("invalid max body bytes: %d", c.Int64("max-body-bytes"))
+	}
+	if c.Duration("request-timeout") < 0 {
+		return fmt.Errorf("invalid request timeout: %v", c.Duration("request-timeout"))
+	}
+	if c.Duration("slow-threshold") < 0 {
+		return fmt.Errorf("invalid slow threshold: %v", c.Duration("slow-threshold"))
+	}
+	if c.Int("max-conns") < 0 {
+		return fmt.Errorf("invalid max conns: %d", c.Int("max-conns"))
+	}
+	if c.Duration("idle-shutdown") < 0 {
+		return fmt.Errorf("invalid idle shutdown: %v", c.Duration("idle-shutdown"))
+	}
+	if c.Bool("tls-auto") {
+		if len(c.StringSlice("domain")) == 0 {
+			return errors.New("tls-auto requires at least one --domain")
+		}
+		if c.Bool("h2c") {
+			return errors.New("tls-auto and h2c cannot be used together")
+		}
+	}
+	return nil
+}
+
+// protoDirConfig returns the path of the config file in the project
+// directory dir, checking that dir has the conventional layout of a
+// config.yaml file and an assets directory.
+func protoDirConfig(dir string) (string, error) {
+	configFile := filepath.Join(dir, ConfigFileName)
+	if _, err := os.Stat(configFile); err != nil {
+		return "", fmt.Errorf("proto dir %q has no %s", dir, ConfigFileName)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, AssetDirName)); err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("proto dir %q has no %s directory", dir, AssetDirName)
+	}
+	return configFile, nil
 }
 
 // BuildCLI creates a cli app to run the capabilities provided by
@@ -45,24 +176,184 @@ func BuildCLI(app Applicator) *cli.Command {
 		Value:   "8000",
 		Usage:   "server network port",
 	}
+	rateLimitFlag := &cli.Float64Flag{
+		Name:  "rate-limit",
+		Value: 0,
+		Usage: "requests per second per client IP (0 is unlimited)",
+	}
+	trustProxyFlag := &cli.BoolFlag{
+		Name:  "trust-proxy",
+		Usage: "use X-Forwarded-For to determine the client IP",
+	}
+	h2cFlag := &cli.BoolFlag{
+		Name:  "h2c",
+		Usage: "serve unencrypted HTTP/2 (h2c), for use behind a proxy",
+	}
+	maxBodyBytesFlag := &cli.Int64Flag{
+		Name:  "max-body-bytes",
+		Value: defaultMaxBodyBytes,
+		Usage: "maximum request body size for non-GET requests (0 is unlimited)",
+	}
+	requestTimeoutFlag := &cli.DurationFlag{
+		Name:  "request-timeout",
+		Usage: "deadline for page and index responses, e.g. 5s (0 is off)",
+	}
+	slowThresholdFlag := &cli.DurationFlag{
+		Name:  "slow-threshold",
+		Value: defaultSlowThreshold,
+		Usage: "log a warning for requests taking longer than this, e.g. 500ms (0 is off)",
+	}
+	maxConnsFlag := &cli.IntFlag{
+		Name:  "max-conns",
+		Usage: "maximum concurrent connections; further connections wait (0 is unlimited)",
+	}
+	idleShutdownFlag := &cli.DurationFlag{
+		Name:  "idle-shutdown",
+		Usage: "shut down after no requests for this long, e.g. 30m (0 is off)",
+	}
+	// tlsFlags are for serving public demos over https with automatic
+	// certificates.
+	tlsFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "tls-auto",
+			Usage: "serve https on port 443 with Let's Encrypt certificates, redirecting http on port 80",
+		},
+		&cli.StringSliceFlag{
+			Name:  "domain",
+			Usage: "domain for automatic certificates (may be repeated)",
+		},
+		&cli.StringFlag{
+			Name:  "cert-cache",
+			Value: defaultCertCache,
+			Usage: "directory for caching automatic certificates",
+		},
+		&cli.BoolFlag{
+			Name:  "hsts",
+			Usage: "require https, setting Strict-Transport-Security and redirecting http requests (with --tls-auto)",
+		},
+	}
+	precompileFlag := &cli.BoolFlag{
+		Name:  "precompile",
+		Usage: "render each page template at startup, failing on template errors",
+	}
+	strictFlag := &cli.BoolFlag{
+		Name:  "strict",
+		Usage: "treat config warnings, such as zones smaller than minTapSize, as errors",
+	}
+	strictTemplatesFlag := &cli.BoolFlag{
+		Name:  "strict-templates",
+		Usage: "fail on templates referencing missing keys rather than rendering '<no value>'",
+	}
+	etagFlag := &cli.BoolFlag{
+		Name:  "etag",
+		Usage: "serve pages and the index with an ETag, answering conditional requests with 304",
+	}
+	showZonesFlag := &cli.BoolFlag{
+		Name:  "show-zones",
+		Usage: "outline each zone with its target to check placement (also with a ?zones=1 query)",
+	}
+	placeholderFlag := &cli.BoolFlag{
+		Name:  "placeholder-images",
+		Usage: "serve a placeholder for images deleted while the server runs, logging a warning",
+	}
+	sessionParamFlag := &cli.StringFlag{
+		Name:  "log-session-param",
+		Usage: "query parameter, such as 'session', identifying a tester's requests, logged as JSON lines",
+	}
+	serverHeaderFlag := &cli.StringFlag{
+		Name:  "server-header",
+		Usage: "Server response header, such as 'MyProto/1.0', or \"\" to omit it",
+	}
+	qrFlag := &cli.BoolFlag{
+		Name:  "qr",
+		Usage: "print a QR code of the index url at startup, for opening on a phone",
+	}
+	pprofFlag := &cli.BoolFlag{
+		Name:  "pprof",
+		Usage: "serve profiling endpoints at /debug/pprof/",
+	}
+	noRecoverFlag := &cli.BoolFlag{
+		Name:  "no-recover",
+		Usage: "do not recover from handler panics, logging the stack trace",
+	}
+	quietFlag := &cli.BoolFlag{
+		Name:    "quiet",
+		Aliases: []string{"q"},
+		Usage:   "suppress startup messages and the access log",
+	}
 
 	serveCmd := &cli.Command{
 		Name:      "serve",
 		Usage:     "Serve content on disk",
-		ArgsUsage: "CONFIG_FILE",
+		ArgsUsage: "CONFIG_FILE|CONFIG_URL (or, with --tar, the config file in the tar stream)",
 		// use the common flags
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			addressFlag,
 			portFlag,
-		},
+			rateLimitFlag,
+			trustProxyFlag,
+			quietFlag,
+			h2cFlag,
+			maxBodyBytesFlag,
+			noRecoverFlag,
+			requestTimeoutFlag,
+			slowThresholdFlag,
+			pprofFlag,
+			maxConnsFlag,
+			precompileFlag,
+			etagFlag,
+			showZonesFlag,
+			placeholderFlag,
+			strictFlag,
+			strictTemplatesFlag,
+			qrFlag,
+			sessionParamFlag,
+			serverHeaderFlag,
+			&cli.BoolFlag{
+				Name:  "cache-pages",
+				Usage: "render each page once at startup and on config reloads, serving the cached html",
+			},
+			&cli.StringFlag{
+				Name:  "tar",
+				Usage: "serve the project from memory, reading it from a tar file or stdin (\"-\")",
+			},
+			&cli.StringFlag{
+				Name:  "overlay",
+				Usage: "config file, such as config.local.yaml, merged over the config file (pages by URL)",
+			},
+			&cli.StringFlag{
+				Name:  "proto-dir",
+				Usage: "serve the config.yaml and assets of a project directory, such as /proto in a container, in place of CONFIG_FILE",
+			},
+			idleShutdownFlag,
+		}, tlsFlags...),
 		// Before runs verification before "Action" is run
 		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
-			if c.NArg() < 1 {
-				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			// with --proto-dir the config file is in the project directory
+			if dir := c.String("proto-dir"); dir != "" {
+				if c.String("tar") != "" || c.NArg() > 0 {
+					return ctx, errors.New("proto-dir cannot be used with tar or CONFIG_FILE")
+				}
+				if _, err := protoDirConfig(dir); err != nil {
+					return ctx, err
+				}
+			} else if c.String("tar") == "" {
+				// with --tar the config file, if provided, is in the tar stream
+				if c.NArg() < 1 {
+					return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+				}
+				configFile := c.Args().First()
+				if _, err := os.Stat(configFile); err != nil && !isRemoteConfig(configFile) {
+					return ctx, fmt.Errorf("config file %q not found", configFile)
+				}
 			}
-			configFile := c.Args().First()
-			if _, err := os.Stat(configFile); err != nil {
-				return ctx, fmt.Errorf("config file %q not found", configFile)
+			if overlay := c.String("overlay"); overlay != "" {
+				if c.String("tar") != "" {
+					return ctx, errors.New("overlay and tar cannot be used together")
+				}
+				if _, err := os.Stat(overlay); err != nil {
+					return ctx, fmt.Errorf("overlay file %q not found", overlay)
+				}
 			}
 			if a := net.ParseIP(c.String("address")); a == nil {
 				return ctx, fmt.Errorf("invalid IP address: %s", c.String("address"))
@@ -70,11 +361,30 @@ func BuildCLI(app Applicator) *cli.Command {
 			if _, err := strconv.Atoi(c.String("port")); err != nil {
 				return ctx, fmt.Errorf("invalid port: %s", c.String("port"))
 			}
+			if err := validateServerOptions(c); err != nil {
+				return ctx, err
+			}
 			return ctx, nil
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
 			configFile := c.Args().First()
-			return app.Serve(c.String("address"), c.String("port"), configFile)
+			options := serverOptions(c)
+			if dir := c.String("proto-dir"); dir != "" {
+				var err error
+				if configFile, err = protoDirConfig(dir); err != nil {
+					return err
+				}
+				// the assets are those of the project directory,
+				// whatever the config's assetsDir
+				options.AssetsDir = AssetDirName
+			}
+			if tarFile := c.String("tar"); tarFile != "" {
+				if configFile == "" {
+					configFile = ConfigFileName
+				}
+				return app.ServeTar(c.String("address"), c.String("port"), tarFile, configFile, options)
+			}
+			return app.Serve(c.String("address"), c.String("port"), configFile, options)
 		},
 	}
 
@@ -83,17 +393,66 @@ func BuildCLI(app Applicator) *cli.Command {
 		Usage: "Serve content on disk with automatic file reloads",
 		Description: `Presently only the yaml file, with a '.yaml' extension, together with
 (by default) the files with a '.html' extension in templates are
-automatically reloaded. The latter can be changed with -s flags.`,
+automatically reloaded. The latter can be changed with -s flags, which
+may also be glob patterns such as '*.tmpl.html'.`,
 		ArgsUsage: "CONFIG_FILE",
 		// use common flags
 		Flags: []cli.Flag{
 			addressFlag,
 			portFlag,
+			rateLimitFlag,
+			trustProxyFlag,
+			quietFlag,
+			h2cFlag,
+			maxBodyBytesFlag,
+			noRecoverFlag,
+			requestTimeoutFlag,
+			slowThresholdFlag,
+			pprofFlag,
+			maxConnsFlag,
+			precompileFlag,
+			etagFlag,
+			showZonesFlag,
+			placeholderFlag,
+			strictFlag,
+			strictTemplatesFlag,
+			sessionParamFlag,
+			serverHeaderFlag,
 			&cli.StringSliceFlag{
 				Name:    "suffix",
 				Aliases: []string{"s"},
 				Value:   []string{"html"},
-				Usage:   "template directory suffixes",
+				Usage:   "template directory suffixes or glob patterns, such as '*.tmpl.html'",
+			},
+			&cli.BoolFlag{
+				Name:  "trace",
+				Usage: "log each development event loop transition",
+			},
+			&cli.IntFlag{
+				Name:  "load-retries",
+				Value: defaultLoadRetries,
+				Usage: "config load retries before waiting for a file fix",
+			},
+			&cli.DurationFlag{
+				Name:  "load-retry-interval",
+				Value: defaultLoadRetryInterval,
+				Usage: "initial interval between config load retries, doubled on each retry",
+			},
+			&cli.StringFlag{
+				Name:  "reload-command",
+				Usage: "shell command, such as 'make css', run on file updates before reloading",
+			},
+			&cli.BoolFlag{
+				Name:  "watch-config-only",
+				Usage: "only reload on config file updates, ignoring template changes",
+			},
+			&cli.DurationFlag{
+				Name:  "reload-delay",
+				Usage: "delay before restarting a server that stopped unexpectedly, such as 200ms, to let the port be released",
+			},
+			&cli.BoolFlag{
+				Name:  "events",
+				Usage: "write a JSON-lines stream of state changes, such as reloads and errors, to stdout, moving the access log to stderr",
 			},
 		},
 		// Before runs verification before "Action" is run
@@ -111,17 +470,246 @@ automatically reloaded. The latter can be changed with -s flags.`,
 			if _, err := strconv.Atoi(c.String("port")); err != nil {
 				return ctx, fmt.Errorf("invalid port: %s", c.String("port"))
 			}
+			if err := validateServerOptions(c); err != nil {
+				return ctx, err
+			}
 			if c.StringSlice("suffix") == nil {
 				return ctx, errors.New("no suffixes provided")
 			}
 			if slices.Contains(c.StringSlice("suffix"), "") {
 				return ctx, errors.New("empty suffix argument provided")
 			}
+			if c.Int("load-retries") < 0 {
+				return ctx, fmt.Errorf("invalid load retries: %d", c.Int("load-retries"))
+			}
+			if c.Duration("load-retry-interval") < 0 {
+				return ctx, fmt.Errorf("invalid load retry interval: %v", c.Duration("load-retry-interval"))
+			}
+			if c.Duration("reload-delay") < 0 {
+				return ctx, fmt.Errorf("invalid reload delay: %v", c.Duration("reload-delay"))
+			}
 			return ctx, nil
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
 			configFile := c.Args().First()
-			return app.ServeInDevelopment(c.String("address"), c.String("port"), c.StringSlice("suffix"), configFile)
+			return app.ServeInDevelopment(c.String("address"), c.String("port"), c.StringSlice("suffix"), configFile, serverOptions(c), developOptions(c))
+		},
+	}
+
+	validateCmd := &cli.Command{
+		Name:      "validate",
+		Usage:     "Validate a config file and its templates",
+		ArgsUsage: "CONFIG_FILE|CONFIG_URL",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "report all page and zone problems, not just the first",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "report the result, with error codes, as JSON",
+			},
+			strictFlag,
+			&cli.BoolFlag{
+				Name:  "check-reachability",
+				Usage: "warn of pages no zone path leads to from the entry page (errors with --strict)",
+			},
+			&cli.StringFlag{
+				Name:  "entry",
+				Usage: "entry page url for --check-reachability (default the config entryURL, the / page or the first page)",
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 1 {
+				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			}
+			configFile := c.Args().First()
+			if _, err := os.Stat(configFile); err != nil && !isRemoteConfig(configFile) {
+				return ctx, fmt.Errorf("config file %q not found", configFile)
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			err := app.Validate(c.Args().First(), ValidateOptions{
+				AllErrors: c.Bool("all"),
+				JSON:      c.Bool("json"),
+				Strict:    c.Bool("strict"),
+
+				CheckReachability: c.Bool("check-reachability"),
+				Entry:             c.String("entry"),
+			})
+			// exit without repeating problems already reported as JSON
+			if errors.Is(err, errValidationReported) {
+				return cli.Exit("", 1)
+			}
+			return err
+		},
+	}
+
+	sitemapCmd := &cli.Command{
+		Name:      "sitemap",
+		Usage:     "Print a JSON description of the site structure",
+		ArgsUsage: "CONFIG_FILE|CONFIG_URL",
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 1 {
+				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			}
+			configFile := c.Args().First()
+			if _, err := os.Stat(configFile); err != nil && !isRemoteConfig(configFile) {
+				return ctx, fmt.Errorf("config file %q not found", configFile)
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return app.Sitemap(c.Args().First())
+		},
+	}
+
+	analyzeCmd := &cli.Command{
+		Name:      "analyze",
+		Usage:     "Print a ranked table of page visits from an access log",
+		ArgsUsage: "CONFIG_FILE|CONFIG_URL LOG_FILE",
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 2 {
+				return ctx, fmt.Errorf("missing required arguments: CONFIG_FILE LOG_FILE")
+			}
+			configFile := c.Args().Get(0)
+			if _, err := os.Stat(configFile); err != nil && !isRemoteConfig(configFile) {
+				return ctx, fmt.Errorf("config file %q not found", configFile)
+			}
+			logFile := c.Args().Get(1)
+			if _, err := os.Stat(logFile); err != nil {
+				return ctx, fmt.Errorf("log file %q not found", logFile)
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return app.Analyze(c.Args().Get(0), c.Args().Get(1))
+		},
+	}
+
+	addPageCmd := &cli.Command{
+		Name:      "add-page",
+		Usage:     "Add a page without zones to a config file",
+		ArgsUsage: "CONFIG_FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "url",
+				Required: true,
+				Usage:    "page url, such as /foo",
+			},
+			&cli.StringFlag{
+				Name:     "title",
+				Required: true,
+				Usage:    "page title",
+			},
+			&cli.StringFlag{
+				Name:     "image",
+				Required: true,
+				Usage:    "page image path in the assets directory, such as images/foo.jpg",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-validate",
+				Usage: "write the config without validating it, such as before the image exists",
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 1 {
+				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			}
+			if _, err := os.Stat(c.Args().First()); err != nil {
+				return ctx, fmt.Errorf("config file %q not found", c.Args().First())
+			}
+			if !strings.HasPrefix(c.String("url"), "/") {
+				return ctx, fmt.Errorf("page url %q must start with '/'", c.String("url"))
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return app.AddPage(c.Args().First(), AddPageOptions{
+				URL:          c.String("url"),
+				Title:        c.String("title"),
+				ImagePath:    c.String("image"),
+				SkipValidate: c.Bool("skip-validate"),
+			})
+		},
+	}
+
+	thumbnailsCmd := &cli.Command{
+		Name:      "thumbnails",
+		Usage:     "Write scaled down page images to the images thumbs directory",
+		ArgsUsage: "CONFIG_FILE",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "width",
+				Value: defaultThumbnailWidth,
+				Usage: "thumbnail width in pixels",
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 1 {
+				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			}
+			if _, err := os.Stat(c.Args().First()); err != nil {
+				return ctx, fmt.Errorf("config file %q not found", c.Args().First())
+			}
+			if c.Int("width") < 1 {
+				return ctx, fmt.Errorf("invalid width: %d", c.Int("width"))
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return app.Thumbnails(c.Args().First(), ThumbnailOptions{Width: c.Int("width")})
+		},
+	}
+
+	pdfCmd := &cli.Command{
+		Name:      "pdf",
+		Usage:     "Write a PDF storyboard of the pages with their zones drawn",
+		ArgsUsage: "CONFIG_FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "out",
+				Value: "proto.pdf",
+				Usage: "PDF file to write",
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 1 {
+				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			}
+			if _, err := os.Stat(c.Args().First()); err != nil {
+				return ctx, fmt.Errorf("config file %q not found", c.Args().First())
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return app.PDF(c.Args().First(), c.String("out"))
+		},
+	}
+
+	exportCmd := &cli.Command{
+		Name:      "export",
+		Usage:     "Write the pages as static html files for a static host",
+		ArgsUsage: "CONFIG_FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "out",
+				Value: "site",
+				Usage: "directory to write the html, images and static files to",
+			},
+		},
+		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
+			if c.NArg() < 1 {
+				return ctx, fmt.Errorf("missing required argument: CONFIG_FILE")
+			}
+			if _, err := os.Stat(c.Args().First()); err != nil {
+				return ctx, fmt.Errorf("config file %q not found", c.Args().First())
+			}
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			return app.Export(c.Args().First(), c.String("out"))
 		},
 	}
 
@@ -146,9 +734,13 @@ automatically reloaded. The latter can be changed with -s flags.`,
 				Value:   ".", // better than os.Getwd
 				Usage:   "directory to write files",
 			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "report the files written as JSON",
+			},
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
-			return app.Init(c.String("directory"))
+			return app.Init(c.String("directory"), InitOptions{JSON: c.Bool("json")})
 		},
 	}
 
@@ -157,10 +749,34 @@ automatically reloaded. The latter can be changed with -s flags.`,
 		Usage:                 "Run the demo server with embedded assets",
 		EnableShellCompletion: true,
 		// use the common flags
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			addressFlag,
 			portFlag,
-		},
+			rateLimitFlag,
+			trustProxyFlag,
+			quietFlag,
+			h2cFlag,
+			maxBodyBytesFlag,
+			noRecoverFlag,
+			requestTimeoutFlag,
+			slowThresholdFlag,
+			pprofFlag,
+			maxConnsFlag,
+			precompileFlag,
+			etagFlag,
+			showZonesFlag,
+			idleShutdownFlag,
+			qrFlag,
+			serverHeaderFlag,
+			&cli.StringFlag{
+				Name:  "example",
+				Usage: "serve the named embedded example instead of the default demo, such as 'mobile'",
+			},
+			&cli.BoolFlag{
+				Name:  "port-scan",
+				Usage: fmt.Sprintf("if the port is in use, try the next %d ports in turn", portScanAttempts),
+			},
+		}, tlsFlags...),
 		// Repeat validation logic (consider sharing).
 		Before: func(ctx context.Context, c *cli.Command) (context.Context, error) {
 			if a := net.ParseIP(c.String("address")); a == nil {
@@ -169,10 +785,13 @@ automatically reloaded. The latter can be changed with -s flags.`,
 			if _, err := strconv.Atoi(c.String("port")); err != nil {
 				return ctx, fmt.Errorf("invalid port: %s", c.String("port"))
 			}
+			if err := validateServerOptions(c); err != nil {
+				return ctx, err
+			}
 			return ctx, nil
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
-			return app.Demo(c.String("address"), c.String("port"))
+			return app.Demo(c.String("address"), c.String("port"), c.String("example"), serverOptions(c))
 		},
 	}
 
@@ -180,7 +799,7 @@ automatically reloaded. The latter can be changed with -s flags.`,
 		Name:        "firstgo",
 		Usage:       ShortUsage,
 		Description: LongDescription,
-		Commands:    []*cli.Command{demoCmd, initCmd, serveCmd, serveInDevelopmentCmd},
+		Commands:    []*cli.Command{demoCmd, initCmd, serveCmd, serveInDevelopmentCmd, validateCmd, sitemapCmd, analyzeCmd, addPageCmd, thumbnailsCmd, pdfCmd, exportCmd},
 	}
 
 	// custom help template.
@@ -10,16 +10,40 @@ import (
 // TestApplication implements the Applicator interface.
 type TestApplication struct{}
 
-func (t *TestApplication) Serve(address, port, configFile string) error {
+func (t *TestApplication) Serve(address, port, configFile string, options ServerOptions) error {
 	return nil
 }
-func (t *TestApplication) ServeInDevelopment(address, port string, templateSuffixes []string, configFile string) error {
+func (t *TestApplication) ServeTar(address, port, tarFile, configName string, options ServerOptions) error {
 	return nil
 }
-func (t *TestApplication) Init(directory string) error {
+func (t *TestApplication) ServeInDevelopment(address, port string, templateSuffixes []string, configFile string, options ServerOptions, devOptions DevelopOptions) error {
 	return nil
 }
-func (t *TestApplication) Demo(address, port string) error {
+func (t *TestApplication) Init(directory string, options InitOptions) error {
+	return nil
+}
+func (t *TestApplication) Validate(configFile string, options ValidateOptions) error {
+	return nil
+}
+func (t *TestApplication) Sitemap(configFile string) error {
+	return nil
+}
+func (t *TestApplication) Analyze(configFile, logFile string) error {
+	return nil
+}
+func (t *TestApplication) AddPage(configFile string, options AddPageOptions) error {
+	return nil
+}
+func (t *TestApplication) Thumbnails(configFile string, options ThumbnailOptions) error {
+	return nil
+}
+func (t *TestApplication) PDF(configFile, out string) error {
+	return nil
+}
+func (t *TestApplication) Export(configFile, out string) error {
+	return nil
+}
+func (t *TestApplication) Demo(address, port, example string, options ServerOptions) error {
 	return nil
 }
 
@@ -49,6 +73,29 @@ func TestParseCLI(t *testing.T) {
 			args:            []string{"program", "serve", "--address", "127.0.0.2"},
 			wantErrContains: "missing required argument",
 		},
+		{
+			name: "serve proto dir",
+			args: []string{"program", "serve", "--proto-dir", "."},
+		},
+		{
+			name:            "serve proto dir with config",
+			args:            []string{"program", "serve", "--proto-dir", ".", "config.yaml"},
+			wantErrContains: "cannot be used with",
+		},
+		{
+			name:            "serve proto dir without config",
+			args:            []string{"program", "serve", "--proto-dir", "assets"},
+			wantErrContains: "has no config.yaml",
+		},
+		{
+			name:            "serve missing overlay",
+			args:            []string{"program", "serve", "--overlay", "nonexistent.yaml", "config.yaml"},
+			wantErrContains: "overlay file",
+		},
+		{
+			name: "serve tar stdin",
+			args: []string{"program", "serve", "--tar", "-"},
+		},
 		{
 			name: "serve help",
 			args: []string{"program", "serve", "-h"},
@@ -63,6 +110,90 @@ func TestParseCLI(t *testing.T) {
 			args:            []string{"program", "serve", "-a", "127.0.0.3", "-p", "eight", "config.yaml"},
 			wantErrContains: "invalid port",
 		},
+		{
+			name: "serve rate limit",
+			args: []string{"program", "serve", "--rate-limit", "5", "--trust-proxy", "config.yaml"},
+		},
+		{
+			name:            "serve invalid rate limit",
+			args:            []string{"program", "serve", "--rate-limit", "-1", "config.yaml"},
+			wantErrContains: "invalid rate limit",
+		},
+		{
+			name: "validate all",
+			args: []string{"program", "validate", "--all", "config.yaml"},
+		},
+		{
+			name: "validate json",
+			args: []string{"program", "validate", "--all", "--json", "config.yaml"},
+		},
+		{
+			name:            "validate no config",
+			args:            []string{"program", "validate"},
+			wantErrContains: "missing required argument",
+		},
+		{
+			name: "sitemap",
+			args: []string{"program", "sitemap", "config.yaml"},
+		},
+		{
+			name: "validate strict",
+			args: []string{"program", "validate", "--strict", "config.yaml"},
+		},
+		{
+			name: "validate check reachability",
+			args: []string{"program", "validate", "--check-reachability", "--entry", "/home", "config.yaml"},
+		},
+		{
+			name: "analyze",
+			args: []string{"program", "analyze", "config.yaml", "README.md"},
+		},
+		{
+			name: "add-page",
+			args: []string{"program", "add-page", "--url", "/foo", "--title", "Foo", "--image", "images/foo.jpg", "config.yaml"},
+		},
+		{
+			name: "thumbnails",
+			args: []string{"program", "thumbnails", "--width", "160", "config.yaml"},
+		},
+		{
+			name:            "thumbnails invalid width",
+			args:            []string{"program", "thumbnails", "--width", "0", "config.yaml"},
+			wantErrContains: "invalid width",
+		},
+		{
+			name: "pdf",
+			args: []string{"program", "pdf", "--out", "proto.pdf", "config.yaml"},
+		},
+		{
+			name: "export",
+			args: []string{"program", "export", "--out", "site", "config.yaml"},
+		},
+		{
+			name:            "add-page missing title",
+			args:            []string{"program", "add-page", "--url", "/foo", "--image", "images/foo.jpg", "config.yaml"},
+			wantErrContains: "title",
+		},
+		{
+			name:            "add-page relative url",
+			args:            []string{"program", "add-page", "--url", "foo", "--title", "Foo", "--image", "images/foo.jpg", "config.yaml"},
+			wantErrContains: "must start with",
+		},
+		{
+			name:            "analyze no log",
+			args:            []string{"program", "analyze", "config.yaml"},
+			wantErrContains: "missing required arguments",
+		},
+		{
+			name:            "analyze missing log",
+			args:            []string{"program", "analyze", "config.yaml", "nonexistent.log"},
+			wantErrContains: "log file",
+		},
+		{
+			name:            "sitemap no config",
+			args:            []string{"program", "sitemap", "nonexistent.yaml"},
+			wantErrContains: "not found",
+		},
 		{
 			name: "init help",
 			args: []string{"program", "init", "-h"},
@@ -75,6 +206,10 @@ func TestParseCLI(t *testing.T) {
 			name: "init ok with tmp dir",
 			args: []string{"program", "init", "-d", "/tmp"},
 		},
+		{
+			name: "init json",
+			args: []string{"program", "init", "--json", "-d", "/tmp"},
+		},
 		{
 			name:            "init failure",
 			args:            []string{"program", "init", "-d", "/_DATA/tmp"},
@@ -92,6 +227,75 @@ func TestParseCLI(t *testing.T) {
 			name: "demo ok no args",
 			args: []string{"program", "demo"},
 		},
+		{
+			name: "serve remote config",
+			args: []string{"program", "serve", "https://example.com/config.yaml"},
+		},
+		{
+			name:            "development remote config",
+			args:            []string{"program", "develop", "https://example.com/config.yaml"},
+			wantErrContains: "not found",
+		},
+		{
+			name: "serve h2c",
+			args: []string{"program", "serve", "--h2c", "config.yaml"},
+		},
+		{
+			name: "serve no recover",
+			args: []string{"program", "serve", "--no-recover", "config.yaml"},
+		},
+		{
+			name:            "serve invalid max body bytes",
+			args:            []string{"program", "serve", "--max-body-bytes", "-1", "config.yaml"},
+			wantErrContains: "invalid max body bytes",
+		},
+		{
+			name: "serve request timeout",
+			args: []string{"program", "serve", "--request-timeout", "5s", "config.yaml"},
+		},
+		{
+			name:            "serve invalid request timeout",
+			args:            []string{"program", "serve", "--request-timeout", "-1s", "config.yaml"},
+			wantErrContains: "invalid request timeout",
+		},
+		{
+			name: "development pprof",
+			args: []string{"program", "develop", "--pprof", "config.yaml"},
+		},
+		{
+			name:            "serve invalid max conns",
+			args:            []string{"program", "serve", "--max-conns", "-1", "config.yaml"},
+			wantErrContains: "invalid max conns",
+		},
+		{
+			name: "serve tls auto",
+			args: []string{"program", "serve", "--tls-auto", "--domain", "example.com", "config.yaml"},
+		},
+		{
+			name:            "serve tls auto no domain",
+			args:            []string{"program", "serve", "--tls-auto", "config.yaml"},
+			wantErrContains: "requires at least one --domain",
+		},
+		{
+			name: "demo idle shutdown",
+			args: []string{"program", "demo", "--idle-shutdown", "30m"},
+		},
+		{
+			name: "demo example",
+			args: []string{"program", "demo", "--example", "mobile"},
+		},
+		{
+			name: "demo quiet",
+			args: []string{"program", "demo", "--quiet"},
+		},
+		{
+			name: "demo server header",
+			args: []string{"program", "demo", "--server-header", ""},
+		},
+		{
+			name: "demo qr",
+			args: []string{"program", "demo", "--qr"},
+		},
 		{
 			name:            "demo invalid address",
 			args:            []string{"program", "demo", "-a", "url", "-p", "8001"},
@@ -105,6 +309,40 @@ func TestParseCLI(t *testing.T) {
 			name: "development only config",
 			args: []string{"program", "develop", "config.yaml"},
 		},
+		{
+			name: "development trace",
+			args: []string{"program", "develop", "--trace", "config.yaml"},
+		},
+		{
+			name: "development load retries",
+			args: []string{"program", "develop", "--load-retries", "5", "--load-retry-interval", "50ms", "config.yaml"},
+		},
+		{
+			name: "development reload command",
+			args: []string{"program", "develop", "--reload-command", "make css", "config.yaml"},
+		},
+		{
+			name: "development log session param",
+			args: []string{"program", "develop", "--log-session-param", "session", "config.yaml"},
+		},
+		{
+			name: "development watch config only",
+			args: []string{"program", "develop", "--watch-config-only", "config.yaml"},
+		},
+		{
+			name: "development reload delay",
+			args: []string{"program", "develop", "--reload-delay", "200ms", "config.yaml"},
+		},
+		{
+			name:            "development invalid reload delay",
+			args:            []string{"program", "develop", "--reload-delay", "-1s", "config.yaml"},
+			wantErrContains: "invalid reload delay",
+		},
+		{
+			name:            "development invalid load retries",
+			args:            []string{"program", "develop", "--load-retries", "-1", "config.yaml"},
+			wantErrContains: "invalid load retries",
+		},
 		{
 			name:            "development no config",
 			args:            []string{"program", "develop", "--address", "127.0.0.2"},
@@ -137,3 +375,63 @@ func TestParseCLI(t *testing.T) {
 		})
 	}
 }
+
+// optionsApplication records the ServerOptions passed to Serve and
+// ServeInDevelopment, and the config file passed to Serve.
+type optionsApplication struct {
+	TestApplication
+	configFile string
+	options    ServerOptions
+}
+
+func (o *optionsApplication) Serve(address, port, configFile string, options ServerOptions) error {
+	o.configFile = configFile
+	o.options = options
+	return nil
+}
+
+func (o *optionsApplication) ServeInDevelopment(address, port string, templateSuffixes []string, configFile string, options ServerOptions, devOptions DevelopOptions) error {
+	o.options = options
+	return nil
+}
+
+// TestCLIStrictTemplates checks that --strict-templates is passed to
+// the serve and develop commands.
+func TestCLIStrictTemplates(t *testing.T) {
+	for _, command := range []string{"serve", "develop"} {
+		for _, strict := range []bool{false, true} {
+			args := []string{"program", command}
+			if strict {
+				args = append(args, "--strict-templates")
+			}
+			app := &optionsApplication{}
+			cmd := BuildCLI(app)
+			cmd.Writer = io.Discard
+			cmd.ErrWriter = io.Discard
+			if err := cmd.Run(context.Background(), append(args, "config.yaml")); err != nil {
+				t.Fatalf("%s unexpected error: %v", command, err)
+			}
+			if got := app.options.StrictTemplates; got != strict {
+				t.Errorf("%s strict templates got %t want %t", command, got, strict)
+			}
+		}
+	}
+}
+
+// TestCLIProtoDir checks that --proto-dir serves the config file and
+// assets directory of the project directory.
+func TestCLIProtoDir(t *testing.T) {
+	app := &optionsApplication{}
+	cmd := BuildCLI(app)
+	cmd.Writer = io.Discard
+	cmd.ErrWriter = io.Discard
+	if err := cmd.Run(context.Background(), []string{"program", "serve", "--proto-dir", "."}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := app.configFile, ConfigFileName; got != want {
+		t.Errorf("config file got %q want %q", got, want)
+	}
+	if got, want := app.options.AssetsDir, AssetDirName; got != want {
+		t.Errorf("assets dir got %q want %q", got, want)
+	}
+}
@@ -6,27 +6,82 @@ package main
 // "page" and its related clickable zones are performed here.
 
 import (
-	"bytes"
+	"cmp"
 	"embed"
 	"errors"
 	"fmt"
 	"html/template"
+	"image"
+	_ "image/gif" // register image decoders
+	_ "image/jpeg"
+	_ "image/png"
 	"io/fs"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/goccy/go-yaml"
+	"github.com/gorilla/mux"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/renderer/html"
 )
 
 // ErrInvalidConfig reports an invalid yaml configuration file, although
-// one that passed parsing.
+// one that passed parsing. Code is a machine-readable identifier of the
+// kind of problem for use by tools.
 type ErrInvalidConfig struct {
+	Code ErrorCode
 	info string
 }
 
+// ErrorCode identifies the kind of problem reported by an
+// ErrInvalidConfig.
+type ErrorCode string
+
+// ErrInvalidConfig codes.
+const (
+	CodeMountFailed       ErrorCode = "MOUNT_FAILED"
+	CodeMissingDirectory  ErrorCode = "MISSING_DIRECTORY"
+	CodeInvalidDirectory  ErrorCode = "INVALID_DIRECTORY"
+	CodeTemplateParse     ErrorCode = "TEMPLATE_PARSE"
+	CodeFaviconNotFound   ErrorCode = "FAVICON_NOT_FOUND"
+	CodeInvalidHeader     ErrorCode = "INVALID_HEADER"
+	CodeInvalidOrigin     ErrorCode = "INVALID_ORIGIN"
+	CodeTooFewPages       ErrorCode = "TOO_FEW_PAGES"
+	CodeDuplicateURL      ErrorCode = "DUPLICATE_URL"
+	CodeInvalidURLPattern ErrorCode = "INVALID_URL_PATTERN"
+	CodeMissingField      ErrorCode = "MISSING_FIELD"
+	CodeNoZones           ErrorCode = "NO_ZONES"
+	CodeInvalidZone       ErrorCode = "INVALID_ZONE"
+	CodeInvalidTarget     ErrorCode = "INVALID_TARGET"
+	CodeInclude           ErrorCode = "INCLUDE_ERROR"
+	CodeUnknownGroup      ErrorCode = "UNKNOWN_GROUP"
+	CodeInvalidBackground ErrorCode = "INVALID_BACKGROUND"
+	CodeSmallZone         ErrorCode = "SMALL_ZONE"
+	CodeInvalidMedia      ErrorCode = "INVALID_MEDIA"
+	CodeInvalidRedirect   ErrorCode = "INVALID_REDIRECT"
+	CodeInvalidAssetsURL  ErrorCode = "INVALID_ASSETS_URL"
+	CodeInvalidDelims     ErrorCode = "INVALID_DELIMS"
+	CodeInvalidTLS        ErrorCode = "INVALID_TLS"
+	CodeUnreachablePage   ErrorCode = "UNREACHABLE_PAGE"
+	CodeInvalidEntryURL   ErrorCode = "INVALID_ENTRY_URL"
+	CodeInvalidBasePath   ErrorCode = "INVALID_BASE_PATH"
+	CodeInvalidTour       ErrorCode = "INVALID_TOUR"
+	CodeInvalidExtension  ErrorCode = "INVALID_EXTENSION"
+	CodeInvalidLang       ErrorCode = "INVALID_LANG"
+	CodeInvalidDelay      ErrorCode = "INVALID_DELAY"
+)
+
 // Error reports the error.
 func (e ErrInvalidConfig) Error() string {
 	return fmt.Sprintf("invalid config or template: %s", e.info)
@@ -37,16 +92,43 @@ const (
 	ConfigFileName = "config.yaml" // TODO: fix to work with supplied config file
 )
 
-var RequiredAssetDirs []string = []string{
-	"templates",
-	"static",
-	"images",
+// assetDirs are the names of the required directories in the assets
+// directory.
+type assetDirs struct {
+	Images    string `yaml:"images"`
+	Static    string `yaml:"static"`
+	Templates string `yaml:"templates"`
+}
+
+// setDefaults sets any unset directory names to the defaults.
+func (a *assetDirs) setDefaults() {
+	if a.Images == "" {
+		a.Images = imageDir
+	}
+	if a.Static == "" {
+		a.Static = staticDir
+	}
+	if a.Templates == "" {
+		a.Templates = templateDir
+	}
+}
+
+// required returns the required directories.
+func (a *assetDirs) required() []string {
+	return []string{a.Templates, a.Static, a.Images}
 }
 
-// md is a markdown goldmark instance using GFM (github markdown) and
-// safety settings (called WithUnsafe).
+// md is a markdown goldmark instance using GFM (github markdown) in
+// goldmark's default safe mode, which omits raw html and dangerous
+// links such as "javascript:" urls.
 var md = goldmark.New(
 	goldmark.WithExtensions(extension.GFM),
+)
+
+// mdUnsafe is a markdown goldmark instance using GFM which passes raw
+// html through (called WithUnsafe), used if notesAllowHTML is set.
+var mdUnsafe = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
 	goldmark.WithRendererOptions(
 		html.WithUnsafe(),
 	),
@@ -66,17 +148,139 @@ type config struct {
 	IndexTemplate string `yaml:"indexTemplate"`
 	Pages         []page `yaml:"pages"`
 
+	// ErrorTemplate is an optional template rendered for internal
+	// errors, such as page template execution errors, receiving the
+	// error message as .Error.
+	ErrorTemplate string `yaml:"errorTemplate"`
+
+	// TemplateDelims are optional left and right template action
+	// delimiters, such as ["[[", "]]"], replacing "{{" and "}}" so
+	// that templates can contain those literally.
+	TemplateDelims []string `yaml:"templateDelims"`
+
+	// SplashTemplate is an optional landing page template served at
+	// "/" in place of the index, which is then only served at
+	// "/index".
+	SplashTemplate string `yaml:"splashTemplate"`
+
+	// OrderedPages is a copy of Pages sorted by page Order for display
+	// in the index and navigation. Routing uses Pages.
+	OrderedPages []page
+
+	// Include lists other yaml files whose pages are appended to Pages
+	// before validation. Only the pages of included files are used.
+	Include []string `yaml:"include"`
+
+	// Redirects map legacy paths with no page of their own, such as
+	// the old URL of a renamed page, to page URLs. They are served as
+	// permanent (301) redirects.
+	Redirects map[string]string `yaml:"redirects"`
+
+	// Groups are named sets of default zone properties applied to
+	// zones with a matching Group.
+	Groups map[string]zoneGroup `yaml:"groups"`
+
+	// Dirs are the names of the images, static and templates
+	// directories in the assets directory.
+	Dirs assetDirs `yaml:"dirs"`
+
+	// Favicon is either inline svg content or a path to the favicon in
+	// the assets directory, by default favicon.svg in the static
+	// directory.
+	Favicon string `yaml:"favicon"`
+
+	// Headers are custom response headers, such as
+	// Content-Security-Policy, applied to all responses.
+	Headers map[string]string `yaml:"headers"`
+
+	// NotesAllowHTML permits raw html in page Notes to be rendered
+	// verbatim. By default raw html is omitted, which matters if notes
+	// come from untrusted sources.
+	NotesAllowHTML bool `yaml:"notesAllowHTML"`
+
+	// AllowedOrigins are the origins, such as "https://example.com",
+	// permitted to make cross-origin (CORS) requests. "*" permits any
+	// origin. No CORS headers are sent if none are set.
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+
+	// MinTapSize is the minimum width and height in pixels of a zone,
+	// such as 44 for mobile prototypes. Smaller zones are logged as a
+	// warning, or reported as an error in strict mode. 0 is off.
+	MinTapSize int `yaml:"minTapSize"`
+
+	// StaticAllowedExtensions optionally restricts the files served
+	// from the static directory to those with the listed extensions,
+	// such as ["css", "js", "svg"], so that stray files are not
+	// served. By default all files are served.
+	StaticAllowedExtensions []string `yaml:"staticAllowedExtensions"`
+
+	// AssetsURL is an optional base url, such as a CDN, from which the
+	// templates reference images and static files. If set the local
+	// images and static directories are checked but not served.
+	AssetsURL string `yaml:"assetsURL"`
+
+	// EntryURL is the url of the page at which the prototype starts,
+	// used by the splash "Start" link, the QR code and the reachability
+	// check. By default this is the page at "/" or else the first page.
+	EntryURL string `yaml:"entryURL"`
+
+	// Lang is the content language of the pages, such as "en" (the
+	// default) or "pt-BR", passed to the templates as .Lang for the
+	// html lang attribute and sent as the Content-Language header.
+	Lang string `yaml:"lang"`
+
+	// Tour is an optional ordered list of page URLs, such as ["/a",
+	// "/b", "/c"], for a guided presentation. Each page of the tour is
+	// given the previous and next tour URLs, which the page template
+	// can bind to the arrow keys.
+	Tour []string `yaml:"tour"`
+
+	// BasePath is an optional path, such as "/proto", under which the
+	// prototype is served by a reverse proxy. Relative links and images
+	// in page Notes are prefixed with it.
+	BasePath string `yaml:"basePath"`
+
+	// TLSMinVersion is the minimum TLS version, such as "1.2" or
+	// "1.3", accepted when serving https. By default Go's minimum is
+	// used.
+	TLSMinVersion string `yaml:"tlsMinVersion"`
+	TLSMin        uint16
+
+	// TLSCiphers optionally restrict the TLS 1.0-1.2 cipher suites,
+	// by standard name, such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	// used when serving https. TLS 1.3 suites are not configurable.
+	TLSCiphers      []string `yaml:"tlsCiphers"`
+	TLSCipherSuites []uint16
+
 	// Assets path (for image, template and static directories) and
 	// associated fs.FS
 	AssetsDir string `yaml:"assetsDir"`
 	AssetsFS  fs.FS
 
+	// TemplatesDir is an optional directory, separate from AssetsDir,
+	// from which PageTemplate and IndexTemplate are parsed. If unset
+	// templates are parsed from AssetsFS.
+	TemplatesDir string `yaml:"templatesDir"`
+	TemplatesFS  fs.FS
+
 	// html templates
-	PageTpl  *template.Template
-	IndexTpl *template.Template
+	PageTpl   *template.Template
+	IndexTpl  *template.Template
+	SplashTpl *template.Template // nil unless SplashTemplate is set
+	ErrorTpl  *template.Template // nil unless ErrorTemplate is set
 
 	pagesByURL   map[string]int
+	urlPatterns  []urlPattern // parameterized page urls
 	embeddedMode bool
+	projectFS    fs.FS  // if set, the assets and includes are read from this fs
+	baseDir      string // if set, relative paths on disk are resolved against this directory
+	allErrors    bool   // report all page and zone errors
+	strict       bool   // report warnings as errors
+
+	strictTemplates bool // templates fail on missing keys rather than render "<no value>"
+
+	checkReachability bool   // report pages unreachable from the entry page
+	entryURL          string // entry page for checkReachability, if set
 }
 
 // validateConfig validates the configuration and also sets fields such
@@ -90,40 +294,157 @@ func (c *config) validateConfig() error {
 		var err error
 		c.AssetsFS, err = fs.Sub(assetsFS, AssetDirName)
 		if err != nil {
-			return ErrInvalidConfig{fmt.Sprintf("could not mount embedded fs: %v", err)}
+			return ErrInvalidConfig{CodeMountFailed, fmt.Sprintf("could not mount embedded fs: %v", err)}
 		}
 	} else {
-		if !dirExists(c.AssetsDir) {
-			return ErrInvalidConfig{fmt.Sprintf("directory %q does not exist", c.AssetsDir)}
+		var err error
+		c.AssetsFS, err = c.mountDir(c.AssetsDir)
+		if err != nil {
+			return ErrInvalidConfig{CodeMissingDirectory, fmt.Sprintf("directory %q does not exist", c.AssetsDir)}
 		}
-		c.AssetsFS = os.DirFS(c.AssetsDir)
 	}
 
-	// Check the required directories in the AssetsFS
-	dir, err := fs.ReadDir(c.AssetsFS, ".")
-	if err != nil {
-		return fmt.Errorf("internal error: could not read filesystem: %v", err)
+	// Attach the templates filesystem.
+	c.TemplatesFS = c.AssetsFS
+	if c.TemplatesDir != "" {
+		var err error
+		c.TemplatesFS, err = c.mountDir(c.TemplatesDir)
+		if err != nil {
+			return ErrInvalidConfig{CodeMissingDirectory, fmt.Sprintf("templates directory %q does not exist", c.TemplatesDir)}
+		}
 	}
-OUTER:
-	for _, req := range RequiredAssetDirs {
-		for _, item := range dir {
-			if req == item.Name() && item.Type().IsDir() {
-				continue OUTER
-			}
+
+	// Check the required directories in the AssetsFS. The templates
+	// directory is not required if templates are loaded from
+	// TemplatesDir.
+	c.Dirs.setDefaults()
+	for _, req := range c.Dirs.required() {
+		if c.TemplatesDir != "" && req == c.Dirs.Templates {
+			continue
+		}
+		if !fs.ValidPath(req) || req == "." {
+			return ErrInvalidConfig{CodeInvalidDirectory, fmt.Sprintf("invalid directory name %q", req)}
+		}
+		d, err := fs.Stat(c.AssetsFS, req)
+		if err != nil || !d.IsDir() {
+			return ErrInvalidConfig{CodeMissingDirectory, fmt.Sprintf("required directory %q not found in filesystem", req)}
+		}
+	}
+
+	var err error
+
+	if c.TemplateDelims != nil && (len(c.TemplateDelims) != 2 || slices.Contains(c.TemplateDelims, "")) {
+		return ErrInvalidConfig{CodeInvalidDelims, fmt.Sprintf("templateDelims must be a left and right delimiter, got %q", c.TemplateDelims)}
+	}
+	if c.PageTpl, err = c.parseTemplate(c.PageTemplate); err != nil {
+		return ErrInvalidConfig{CodeTemplateParse, fmt.Sprintf("pageTemplate parsing error: %v", err)}
+	}
+	if c.IndexTpl, err = c.parseTemplate(c.IndexTemplate); err != nil {
+		return ErrInvalidConfig{CodeTemplateParse, fmt.Sprintf("indexTemplate parsing error: %v", err)}
+	}
+	if c.SplashTemplate != "" {
+		if c.SplashTpl, err = c.parseTemplate(c.SplashTemplate); err != nil {
+			return ErrInvalidConfig{CodeTemplateParse, fmt.Sprintf("splashTemplate parsing error: %v", err)}
+		}
+	}
+	if c.ErrorTemplate != "" {
+		if c.ErrorTpl, err = c.parseTemplate(c.ErrorTemplate); err != nil {
+			return ErrInvalidConfig{CodeTemplateParse, fmt.Sprintf("errorTemplate parsing error: %v", err)}
+		}
+	}
+
+	// Check a path based favicon exists.
+	if c.Favicon == "" {
+		c.Favicon = path.Join(c.Dirs.Static, "favicon.svg")
+	}
+	if !isInlineSVG(c.Favicon) {
+		if _, err := fs.Stat(c.AssetsFS, c.Favicon); err != nil {
+			return ErrInvalidConfig{CodeFaviconNotFound, fmt.Sprintf("favicon %q not found", c.Favicon)}
+		}
+	}
+
+	// Check custom headers are well-formed.
+	for k, v := range c.Headers {
+		if !validHeaderName(k) {
+			return ErrInvalidConfig{CodeInvalidHeader, fmt.Sprintf("invalid header name %q", k)}
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return ErrInvalidConfig{CodeInvalidHeader, fmt.Sprintf("invalid value for header %q", k)}
+		}
+	}
+
+	// Check allowed origins are "*" or a scheme and host.
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return ErrInvalidConfig{CodeInvalidOrigin, fmt.Sprintf("invalid allowed origin %q", o)}
 		}
-		return fmt.Errorf("required directory %q not found in filesystem", req)
 	}
 
-	if c.PageTpl, err = template.ParseFS(c.AssetsFS, c.PageTemplate); err != nil {
-		return ErrInvalidConfig{fmt.Sprintf("pageTemplate parsing error: %v", err)}
+	// Check the assets url is an absolute http(s) url, without a
+	// trailing slash for joining in templates.
+	if c.AssetsURL != "" {
+		u, err := url.Parse(c.AssetsURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidConfig{CodeInvalidAssetsURL, fmt.Sprintf("invalid assets url %q", c.AssetsURL)}
+		}
+		c.AssetsURL = strings.TrimSuffix(c.AssetsURL, "/")
+	}
+
+	// Check the content language, defaulting to english.
+	if c.Lang == "" {
+		c.Lang = defaultLang
+	}
+	if !langRe.MatchString(c.Lang) {
+		return ErrInvalidConfig{CodeInvalidLang, fmt.Sprintf("invalid lang %q", c.Lang)}
 	}
-	if c.IndexTpl, err = template.ParseFS(c.AssetsFS, c.IndexTemplate); err != nil {
-		return ErrInvalidConfig{fmt.Sprintf("indexTemplate parsing error: %v", err)}
+
+	// Normalise the static extensions to lower case with a leading
+	// dot, such as ".css", for matching against file names.
+	for i, ext := range c.StaticAllowedExtensions {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext == "" || strings.ContainsAny(ext, "./\\") {
+			return ErrInvalidConfig{CodeInvalidExtension, fmt.Sprintf("invalid static extension %q", c.StaticAllowedExtensions[i])}
+		}
+		c.StaticAllowedExtensions[i] = "." + ext
+	}
+
+	// Check the base path is an absolute path, without a trailing
+	// slash for joining to note links. A base path of "/" is the same
+	// as none.
+	if c.BasePath == "/" {
+		c.BasePath = ""
+	}
+	if c.BasePath != "" {
+		if !strings.HasPrefix(c.BasePath, "/") || path.Clean(c.BasePath) != strings.TrimSuffix(c.BasePath, "/") {
+			return ErrInvalidConfig{CodeInvalidBasePath, fmt.Sprintf("invalid base path %q", c.BasePath)}
+		}
+		c.BasePath = strings.TrimSuffix(c.BasePath, "/")
+	}
+
+	// Resolve the tls settings.
+	if c.TLSMinVersion != "" {
+		v, ok := tlsVersions[c.TLSMinVersion]
+		if !ok {
+			return ErrInvalidConfig{CodeInvalidTLS, fmt.Sprintf("invalid tlsMinVersion %q, want one of 1.0, 1.1, 1.2 or 1.3", c.TLSMinVersion)}
+		}
+		c.TLSMin = v
+	}
+	c.TLSCipherSuites = nil
+	for _, name := range c.TLSCiphers {
+		id, ok := tlsCipherSuite(name)
+		if !ok {
+			return ErrInvalidConfig{CodeInvalidTLS, fmt.Sprintf("unknown, insecure or TLS 1.3 tls cipher %q; TLS 1.3 ciphers are not configurable", name)}
+		}
+		c.TLSCipherSuites = append(c.TLSCipherSuites, id)
 	}
 
 	// Ensure at least two pages are defined.
 	if len(c.Pages) < 2 {
-		return ErrInvalidConfig{"at least two pages must be defined"}
+		return ErrInvalidConfig{CodeTooFewPages, "at least two pages must be defined"}
 	}
 
 	// Register of page and zone urls to ensure that the latter only
@@ -132,91 +453,748 @@ OUTER:
 	// values are used with the same URL the last will be reported.
 	c.pagesByURL = map[string]int{}
 
-	for ii, pg := range c.Pages {
-		if pg.URL == "" {
-			return ErrInvalidConfig{fmt.Sprintf("url empty for page %d (%s)", ii, pg.Title)}
-		}
-		if pg.Title == "" {
-			return ErrInvalidConfig{fmt.Sprintf("title empty for page %d (%s)", ii, pg.URL)}
+	// Page and zone problems are collected in errs. Unless allErrors is
+	// set, only the first problem is reported.
+	var errs []error
+	failFast := func() error {
+		if len(errs) > 0 && !c.allErrors {
+			return errs[0]
 		}
-		if pg.ImagePath == "" {
-			return ErrInvalidConfig{fmt.Sprintf("image path empty for page %d (%s)", ii, pg.Title)}
+		return nil
+	}
+
+	for ii, pg := range c.Pages {
+		errs = append(errs, pageErrors(ii, pg)...)
+		if pg.MediaType == "" {
+			c.Pages[ii].MediaType = mediaImage
 		}
-		if len(pg.Zones) < 1 {
-			return ErrInvalidConfig{fmt.Sprintf("no zones defined for page %d (%s)", ii, pg.Title)}
+		errs = append(errs, c.mediaErrors(ii, c.Pages[ii])...)
+		errs = append(errs, c.pageFaviconErrors(ii, pg)...)
+		if pg.URL != "" {
+			if pg.URL == "/" && c.SplashTemplate != "" {
+				errs = append(errs, ErrInvalidConfig{CodeDuplicateURL, fmt.Sprintf("URL for page %d (%s) is served by the splashTemplate", ii, pg.URL)})
+			} else if c.hasURL(pg.URL) {
+				errs = append(errs, ErrInvalidConfig{CodeDuplicateURL, fmt.Sprintf("URL for page %d (%s) already exists", ii, pg.URL)})
+			} else {
+				c.pagesByURL[pg.URL] = ii
+				if err := c.addURLPattern(ii, pg.URL); err != nil {
+					errs = append(errs, err)
+				}
+			}
 		}
-		if c.hasURL(pg.URL) {
-			return ErrInvalidConfig{fmt.Sprintf("URL for page %d (%s) already exists", ii, pg.URL)}
+		if err := failFast(); err != nil {
+			return err
 		}
-		c.pagesByURL[pg.URL] = ii
 
 		// Note processing
 		if pg.Note == "" {
 			continue
 		}
-		var buf bytes.Buffer
-		if err := md.Convert([]byte(pg.Note), &buf); err != nil {
-			return fmt.Errorf("error processing markdown for page %q: %w", pg.URL, err)
+		renderer := md
+		if c.NotesAllowHTML {
+			renderer = mdUnsafe
+		}
+		note, err := renderNote(renderer, pg.Note, pg.URL, c.BasePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error processing markdown for page %q: %w", pg.URL, err))
+			if err := failFast(); err != nil {
+				return err
+			}
+			continue
+		}
+		c.Pages[ii].NoteHTML = template.HTML(note)
+	}
+
+	// Redirect targets must be page URLs, and redirected paths must not
+	// be pages themselves.
+	for _, from := range slices.Sorted(maps.Keys(c.Redirects)) {
+		to := c.Redirects[from]
+		if !strings.HasPrefix(from, "/") {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidRedirect, fmt.Sprintf("redirect path %q must start with '/'", from)})
+		} else if _, ok := c.pageForURL(from); ok {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidRedirect, fmt.Sprintf("redirect path %q is a page URL", from)})
+		}
+		if _, ok := c.pageForURL(to); !ok {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidRedirect, fmt.Sprintf("redirect target %q for %q is not a page URL", to, from)})
+		}
+		if err := failFast(); err != nil {
+			return err
+		}
+	}
+
+	for i, u := range c.Tour {
+		if !c.hasURL(u) {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidTour, fmt.Sprintf("tour url %q is not a page URL", u)})
+		} else if isURLPattern(u) {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidTour, fmt.Sprintf("tour url %q has path variables", u)})
+		} else if slices.Contains(c.Tour[:i], u) {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidTour, fmt.Sprintf("tour url %q is repeated", u)})
+		}
+		if err := failFast(); err != nil {
+			return err
+		}
+	}
+
+	if c.EntryURL != "" {
+		if _, ok := c.pageForURL(c.EntryURL); !ok {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidEntryURL, fmt.Sprintf("entry url %q is not a page URL", c.EntryURL)})
+		} else if isURLPattern(c.EntryURL) {
+			errs = append(errs, ErrInvalidConfig{CodeInvalidEntryURL, fmt.Sprintf("entry url %q has path variables", c.EntryURL)})
+		}
+		if err := failFast(); err != nil {
+			return err
 		}
-		c.Pages[ii].NoteHTML = template.HTML(buf.String())
 	}
 
 	for ii, pg := range c.Pages {
 		for zi, zo := range pg.Zones {
-			if zo.Target == "" {
-				return ErrInvalidConfig{fmt.Sprintf(
-					"page %d zone %d empty 'Target' value",
-					ii, zi,
-				)}
+			// Merge the group defaults into the zone.
+			if zo.Group != "" {
+				g, ok := c.Groups[zo.Group]
+				if ok {
+					zo = g.apply(zo)
+					c.Pages[ii].Zones[zi] = zo
+				} else {
+					errs = append(errs, ErrInvalidConfig{CodeUnknownGroup, fmt.Sprintf(
+						"unknown Zone Group %s for page %s (%d) zone %d",
+						zo.Group,
+						pg.Title,
+						ii,
+						zi,
+					)})
+				}
 			}
-			if zo.Right < zo.Left || zo.Right == 0 {
-				return ErrInvalidConfig{fmt.Sprintf(
-					"page %d zone %d invalid 'Right' value of %d",
-					ii, zi, zo.Right,
-				)}
+			if zo.Viewport == "" {
+				zo.Viewport = viewportAny
+				c.Pages[ii].Zones[zi].Viewport = viewportAny
 			}
-			if zo.Bottom < zo.Top || zo.Bottom == 0 {
-				return ErrInvalidConfig{fmt.Sprintf(
-					"page %d zone %d invalid 'Bottom' value of %d",
-					ii, zi, zo.Bottom,
-				)}
+			errs = append(errs, zoneErrors(ii, zi, zo)...)
+			if c.MinTapSize > 0 && (zo.Width() < c.MinTapSize || zo.Height() < c.MinTapSize) {
+				msg := fmt.Sprintf(
+					"page %s (%d) zone %d size %dx%d is smaller than the minimum tap size of %d",
+					pg.Title, ii, zi, zo.Width(), zo.Height(), c.MinTapSize,
+				)
+				if c.strict {
+					errs = append(errs, ErrInvalidConfig{CodeSmallZone, msg})
+				} else {
+					log.Printf("config warning: %s", msg)
+				}
 			}
-			pgIdx, ok := c.pagesByURL[zo.Target]
-			if !ok {
-				return ErrInvalidConfig{fmt.Sprintf(
-					"invalid Zone Target URL %s for page %s (%d) zone %d",
-					zo.Target,
-					pg.Title,
-					ii,
-					zi,
-				)}
+			// External, back and overlay targets are not checked
+			// against the pages.
+			if zo.Target == backTarget {
+				c.Pages[ii].Zones[zi].Back = true
+				c.Pages[ii].Zones[zi].TargetTitle = "Back"
+			} else if name, ok := strings.CutPrefix(zo.Target, "#"); ok {
+				if name == "" {
+					errs = append(errs, ErrInvalidConfig{CodeInvalidTarget, fmt.Sprintf(
+						"empty Zone Target overlay name for page %s (%d) zone %d",
+						pg.Title,
+						ii,
+						zi,
+					)})
+				}
+				c.Pages[ii].Zones[zi].Overlay = name
+				c.Pages[ii].Zones[zi].TargetTitle = overlayTitle(name)
+			} else if isExternalURL(zo.Target) {
+				c.Pages[ii].Zones[zi].External = true
+				c.Pages[ii].Zones[zi].TargetTitle = zo.Label
+				if zo.Label == "" {
+					u, _ := url.Parse(zo.Target)
+					c.Pages[ii].Zones[zi].TargetTitle = u.Host
+				}
+			} else if zo.Target != "" {
+				pgIdx, ok := c.pageForURL(zo.Target)
+				if ok {
+					c.Pages[ii].Zones[zi].TargetTitle = c.Pages[pgIdx].Title
+				} else {
+					errs = append(errs, ErrInvalidConfig{CodeInvalidTarget, fmt.Sprintf(
+						"invalid Zone Target URL %s for page %s (%d) zone %d",
+						zo.Target,
+						pg.Title,
+						ii,
+						zi,
+					)})
+				}
+			}
+			// Alternative mouse button targets must be page URLs.
+			for _, alt := range []struct{ name, target string }{
+				{"RightTarget", zo.RightTarget},
+				{"MiddleTarget", zo.MiddleTarget},
+			} {
+				if _, ok := c.pageForURL(alt.target); alt.target != "" && !ok {
+					errs = append(errs, ErrInvalidConfig{CodeInvalidTarget, fmt.Sprintf(
+						"invalid Zone %s URL %s for page %s (%d) zone %d",
+						alt.name,
+						alt.target,
+						pg.Title,
+						ii,
+						zi,
+					)})
+				}
+			}
+			if err := failFast(); err != nil {
+				return err
+			}
+		}
+		if aa := pg.AutoAdvance; aa != nil {
+			if _, ok := c.pageForURL(aa.Target); !ok || isURLPattern(aa.Target) {
+				errs = append(errs, ErrInvalidConfig{CodeInvalidTarget, fmt.Sprintf("invalid AutoAdvance Target URL %q for page %s (%d)", aa.Target, pg.Title, ii)})
+			}
+			if aa.AfterMs <= 0 {
+				errs = append(errs, ErrInvalidConfig{CodeInvalidDelay, fmt.Sprintf("AutoAdvance AfterMs %d must be positive for page %s (%d)", aa.AfterMs, pg.Title, ii)})
+			}
+			if err := failFast(); err != nil {
+				return err
 			}
-			c.Pages[ii].Zones[zi].TargetTitle = c.Pages[pgIdx].Title
 		}
 	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Record image sizes and the zone positions as percentages of these
+	// for responsive templates. Images that cannot be decoded, such as
+	// svg images, are skipped.
+	for ii, pg := range c.Pages {
+		width, height, ok := imageSize(c.AssetsFS, pg.ImagePath)
+		if !ok {
+			continue
+		}
+		c.Pages[ii].ImageWidth, c.Pages[ii].ImageHeight = width, height
+		for zi := range pg.Zones {
+			c.Pages[ii].Zones[zi].setPercentages(width, height)
+		}
+	}
+
+	if c.checkReachability {
+		if errs := c.reachabilityErrors(c.entryURL); len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	}
+
+	c.setTargets()
+	c.setSteps()
+	c.OrderedPages = orderPages(c.Pages)
 	return nil
 }
 
+// setTargets sets the distinct zone Targets of each page, in zone
+// order, for use in templates such as the index. Back and overlay
+// targets are omitted as they do not lead to pages.
+func (c *config) setTargets() {
+	for ii, pg := range c.Pages {
+		targets := []pageTarget{}
+		seen := map[string]bool{}
+		for _, zo := range pg.Zones {
+			if zo.Back || zo.Overlay != "" || seen[zo.Target] {
+				continue
+			}
+			seen[zo.Target] = true
+			targets = append(targets, pageTarget{Target: zo.Target, TargetTitle: zo.TargetTitle})
+		}
+		c.Pages[ii].Targets = targets
+	}
+}
+
+// setSteps sets the StepIndex and StepCount of each page if the pages
+// form a simple linear chain: starting from the first page, each page
+// has at most one zone Target leading to a page not yet visited, and
+// every page is visited. Targets of visited pages, such as "back"
+// zones, are ignored. Otherwise the steps are left as zero.
+func (c *config) setSteps() {
+	chain := []int{}
+	visited := map[int]bool{}
+	for ii := 0; ; {
+		chain = append(chain, ii)
+		visited[ii] = true
+		next := map[int]bool{}
+		for _, zo := range c.Pages[ii].Zones {
+			if zo.External {
+				continue
+			}
+			if pgIdx, ok := c.pageForURL(zo.Target); ok && !visited[pgIdx] {
+				next[pgIdx] = true
+			}
+		}
+		if len(next) != 1 {
+			if len(next) > 1 {
+				return
+			}
+			break
+		}
+		for pgIdx := range next {
+			ii = pgIdx
+		}
+	}
+	if len(chain) != len(c.Pages) {
+		return
+	}
+	for step, ii := range chain {
+		c.Pages[ii].StepIndex = step + 1
+		c.Pages[ii].StepCount = len(chain)
+	}
+}
+
+// orderPages returns a copy of pages stably sorted by Order, with
+// pages without an Order retaining their config order after those
+// with one.
+func orderPages(pages []page) []page {
+	ordered := slices.Clone(pages)
+	slices.SortStableFunc(ordered, func(a, b page) int {
+		switch {
+		case a.Order == nil && b.Order == nil:
+			return 0
+		case a.Order == nil:
+			return 1
+		case b.Order == nil:
+			return -1
+		}
+		return cmp.Compare(*a.Order, *b.Order)
+	})
+	return ordered
+}
+
+// imageSize returns the dimensions of the image at path in fsys, if it
+// can be decoded.
+func imageSize(fsys fs.FS, path string) (width, height int, ok bool) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	ic, _, err := image.DecodeConfig(f)
+	if err != nil || ic.Width == 0 || ic.Height == 0 {
+		return 0, 0, false
+	}
+	return ic.Width, ic.Height, true
+}
+
+// pageErrors reports the problems with the required fields of page
+// pg, the ii'th page.
+func pageErrors(ii int, pg page) []error {
+	var errs []error
+	if pg.URL == "" {
+		errs = append(errs, ErrInvalidConfig{CodeMissingField, fmt.Sprintf("url empty for page %d (%s)", ii, pg.Title)})
+	}
+	if pg.Title == "" {
+		errs = append(errs, ErrInvalidConfig{CodeMissingField, fmt.Sprintf("title empty for page %d (%s)", ii, pg.URL)})
+	}
+	if pg.ImagePath == "" {
+		errs = append(errs, ErrInvalidConfig{CodeMissingField, fmt.Sprintf("image path empty for page %d (%s)", ii, pg.Title)})
+	}
+	if len(pg.Zones) < 1 && !pg.Terminal {
+		errs = append(errs, ErrInvalidConfig{CodeNoZones, fmt.Sprintf("no zones defined for page %d (%s)", ii, pg.Title)})
+	}
+	if pg.Background != "" && !backgroundRe.MatchString(pg.Background) {
+		errs = append(errs, ErrInvalidConfig{CodeInvalidBackground, fmt.Sprintf("invalid background %q for page %d (%s)", pg.Background, ii, pg.Title)})
+	}
+	if pg.Lang != "" && !langRe.MatchString(pg.Lang) {
+		errs = append(errs, ErrInvalidConfig{CodeInvalidLang, fmt.Sprintf("invalid lang %q for page %d (%s)", pg.Lang, ii, pg.Title)})
+	}
+	return errs
+}
+
+// Page media types.
+const (
+	mediaImage = "image"
+	mediaVideo = "video"
+)
+
+// mediaExtensions are the file extensions permitted for each media
+// type.
+var mediaExtensions = map[string][]string{
+	mediaImage: {".avif", ".gif", ".jpeg", ".jpg", ".png", ".svg", ".webp"},
+	mediaVideo: {".mp4", ".ogv", ".webm"},
+}
+
+// mediaErrors reports if the MediaType of the ii'th page pg is unknown
+// or does not match the extension of its ImagePath or, for video pages,
+// if the ImagePath does not exist in the assets filesystem. The images
+// of image pages are checked when the server is built, so that missing
+// images can be replaced by placeholders.
+func (c *config) mediaErrors(ii int, pg page) []error {
+	exts, ok := mediaExtensions[pg.MediaType]
+	if !ok {
+		return []error{ErrInvalidConfig{CodeInvalidMedia, fmt.Sprintf("unknown media type %q for page %d (%s)", pg.MediaType, ii, pg.Title)}}
+	}
+	if pg.ImagePath == "" {
+		return nil
+	}
+	if !slices.Contains(exts, strings.ToLower(path.Ext(pg.ImagePath))) {
+		return []error{ErrInvalidConfig{CodeInvalidMedia, fmt.Sprintf("%s media type does not match %q for page %d (%s)", pg.MediaType, pg.ImagePath, ii, pg.Title)}}
+	}
+	if pg.MediaType != mediaVideo {
+		return nil
+	}
+	if _, err := fs.Stat(c.AssetsFS, pg.ImagePath); err != nil {
+		return []error{ErrInvalidConfig{CodeInvalidMedia, fmt.Sprintf("%s %q not found for page %d (%s)", pg.MediaType, pg.ImagePath, ii, pg.Title)}}
+	}
+	return nil
+}
+
+// pageFaviconErrors reports if the Favicon of the ii'th page pg, if
+// set, is not a file in the static directory.
+func (c *config) pageFaviconErrors(ii int, pg page) []error {
+	if pg.Favicon == "" {
+		return nil
+	}
+	if !fs.ValidPath(pg.Favicon) || !strings.HasPrefix(pg.Favicon, c.Dirs.Static+"/") {
+		return []error{ErrInvalidConfig{CodeFaviconNotFound, fmt.Sprintf("favicon %q not in the static directory for page %d (%s)", pg.Favicon, ii, pg.Title)}}
+	}
+	if d, err := fs.Stat(c.AssetsFS, pg.Favicon); err != nil || d.IsDir() {
+		return []error{ErrInvalidConfig{CodeFaviconNotFound, fmt.Sprintf("favicon %q not found for page %d (%s)", pg.Favicon, ii, pg.Title)}}
+	}
+	return nil
+}
+
+// defaultLang is the default content language.
+const defaultLang = "en"
+
+// langRe matches a BCP 47 language tag, such as "en" or "pt-BR".
+var langRe = regexp.MustCompile(`^[a-zA-Z]{2,8}(-[a-zA-Z0-9]{1,8})*$`)
+
+// backgroundRe matches a css hex color, such as "#eee" or "#f0f0f0",
+// or a named color, such as "whitesmoke".
+var backgroundRe = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|[a-zA-Z]+)$`)
+
+// zoneErrors reports the problems with the fields of zone zo, the zi'th
+// zone of the ii'th page, other than the validity of its target.
+func zoneErrors(ii, zi int, zo pageZone) []error {
+	var errs []error
+	if zo.Target == "" {
+		errs = append(errs, ErrInvalidConfig{CodeMissingField, fmt.Sprintf(
+			"page %d zone %d empty 'Target' value",
+			ii, zi,
+		)})
+	}
+	if zo.Right < zo.Left || zo.Right == 0 {
+		errs = append(errs, ErrInvalidConfig{CodeInvalidZone, fmt.Sprintf(
+			"page %d zone %d invalid 'Right' value of %d",
+			ii, zi, zo.Right,
+		)})
+	}
+	if zo.Bottom < zo.Top || zo.Bottom == 0 {
+		errs = append(errs, ErrInvalidConfig{CodeInvalidZone, fmt.Sprintf(
+			"page %d zone %d invalid 'Bottom' value of %d",
+			ii, zi, zo.Bottom,
+		)})
+	}
+	switch zo.Viewport {
+	case viewportAny, viewportMobile, viewportDesktop:
+	default:
+		errs = append(errs, ErrInvalidConfig{CodeInvalidZone, fmt.Sprintf(
+			"page %d zone %d invalid 'Viewport' value of %q",
+			ii, zi, zo.Viewport,
+		)})
+	}
+	return errs
+}
+
+// overlayTitle derives the TargetTitle of an overlay zone from the
+// overlay name, such as "Detail card" for "detail-card".
+func overlayTitle(name string) string {
+	title := strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	r, size := utf8.DecodeRuneInString(title)
+	if size == 0 {
+		return title
+	}
+	return string(unicode.ToUpper(r)) + title[size:]
+}
+
+// isExternalURL reports if target is an absolute http or https url.
+func isExternalURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isInlineSVG reports if s looks like inline svg content rather than a
+// path.
+func isInlineSVG(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "<svg") || strings.HasPrefix(s, "<?xml")
+}
+
+// validHeaderName reports if s is a valid http header field name, being
+// a non-empty RFC 7230 token.
+func validHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// mountDir returns an fs.FS for the directory dir, either in the
+// project filesystem, if set, or on disk.
+func (c *config) mountDir(dir string) (fs.FS, error) {
+	if c.projectFS == nil {
+		dir = c.resolvePath(dir)
+		if !dirExists(dir) {
+			return nil, fs.ErrNotExist
+		}
+		return os.DirFS(dir), nil
+	}
+	dir = path.Clean(dir)
+	d, err := fs.Stat(c.projectFS, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !d.IsDir() {
+		return nil, fs.ErrNotExist
+	}
+	return fs.Sub(c.projectFS, dir)
+}
+
+// readFile reads the named file from the project filesystem, if set,
+// or from disk.
+func (c *config) readFile(name string) ([]byte, error) {
+	if c.projectFS == nil {
+		return os.ReadFile(c.resolvePath(name))
+	}
+	return fs.ReadFile(c.projectFS, path.Clean(name))
+}
+
+// parseTemplate parses the template file name from the templates
+// filesystem with the template functions and any TemplateDelims.
+func (c *config) parseTemplate(name string) (*template.Template, error) {
+	tpl := template.New(path.Base(name)).Funcs(templateFuncs)
+	if len(c.TemplateDelims) == 2 {
+		tpl = tpl.Delims(c.TemplateDelims[0], c.TemplateDelims[1])
+	}
+	if c.strictTemplates {
+		tpl = tpl.Option("missingkey=error")
+	}
+	return tpl.ParseFS(c.TemplatesFS, name)
+}
+
+// resolvePath resolves the relative path p on disk against the config's
+// base directory, if set. Absolute paths, and paths when no base
+// directory is set, are returned unchanged.
+func (c *config) resolvePath(p string) string {
+	if c.baseDir == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(c.baseDir, p)
+}
+
 // hasURL determines if url is in the pages URL field.
 func (c *config) hasURL(s string) bool {
 	_, ok := c.pagesByURL[s]
 	return ok
 }
 
+// urlPattern is a parameterized page url, such as "/item/{id}", using
+// gorilla/mux path variables.
+type urlPattern struct {
+	page  int
+	route *mux.Route
+}
+
+// isURLPattern reports if a page url contains mux path variables.
+func isURLPattern(u string) bool {
+	return strings.Contains(u, "{")
+}
+
+// addURLPattern registers the url of the ii'th page if it is
+// parameterized, reporting an error if the pattern is invalid.
+func (c *config) addURLPattern(ii int, u string) error {
+	if !isURLPattern(u) {
+		return nil
+	}
+	route := mux.NewRouter().Path(u)
+	if err := route.GetError(); err != nil {
+		return ErrInvalidConfig{CodeInvalidURLPattern, fmt.Sprintf("invalid URL pattern for page %d (%s): %v", ii, u, err)}
+	}
+	c.urlPatterns = append(c.urlPatterns, urlPattern{ii, route})
+	return nil
+}
+
+// pageForURL returns the index of the page served at target, matching
+// parameterized page urls by pattern if there is no literal match.
+func (c *config) pageForURL(target string) (int, bool) {
+	if ii, ok := c.pagesByURL[target]; ok {
+		return ii, true
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return 0, false
+	}
+	req := &http.Request{Method: http.MethodGet, URL: u}
+	for _, up := range c.urlPatterns {
+		if up.route.Match(req, &mux.RouteMatch{}) {
+			return up.page, true
+		}
+	}
+	return 0, false
+}
+
 // newConfig creates and validates a new config from reading a yaml
 // file, initialising in embedded mode or not.
 func newConfig(b []byte, embeddedMode bool) (*config, error) {
+	c, err := parseConfig(b, embeddedMode)
+	if err != nil {
+		return nil, err
+	}
+	err = c.validateConfig()
+	return c, err
+}
+
+// newConfigAllErrors is like newConfig but reports all page and zone
+// validation errors, joined with errors.Join, rather than only the
+// first.
+func newConfigAllErrors(b []byte, embeddedMode bool) (*config, error) {
+	c, err := parseConfig(b, embeddedMode)
+	if err != nil {
+		return nil, err
+	}
+	c.allErrors = true
+	err = c.validateConfig()
+	return c, err
+}
+
+// configOptions are options for validating a config.
+type configOptions struct {
+	allErrors bool // report all page and zone problems
+	strict    bool // treat warnings, such as small zones, as errors
+
+	// strictTemplates parses templates with "missingkey=error", so
+	// that a template referencing a missing key fails on execution
+	// rather than rendering "<no value>".
+	strictTemplates bool
+
+	// checkReachability reports pages which cannot be reached from
+	// entryURL, or by default from the config's entry page, by
+	// following zone targets; see reachabilityErrors.
+	checkReachability bool
+	entryURL          string
+
+	// overlay is yaml merged over the config before parsing; see
+	// mergeConfigOverlay.
+	overlay []byte
+
+	// assetsDir replaces the assetsDir of the config, if set, such as
+	// to serve the assets of a --proto-dir project directory.
+	assetsDir string
+}
+
+// newConfigDir creates and validates a new config from reading a yaml
+// file located in the directory dir, resolving the relative assetsDir,
+// templatesDir and include paths of the config against dir rather than
+// the working directory.
+func newConfigDir(b []byte, dir string, opts configOptions) (*config, error) {
+	if len(opts.overlay) > 0 {
+		var err error
+		if b, err = mergeConfigOverlay(b, opts.overlay); err != nil {
+			return nil, err
+		}
+	}
+	c, err := parseConfigFS(b, false, nil, dir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.assetsDir != "" {
+		c.AssetsDir = opts.assetsDir
+	}
+	c.allErrors = opts.allErrors
+	c.strict = opts.strict
+	c.strictTemplates = opts.strictTemplates
+	c.checkReachability = opts.checkReachability
+	c.entryURL = opts.entryURL
+	err = c.validateConfig()
+	return c, err
+}
+
+// newConfigFS creates and validates a new config from reading a yaml
+// file, with the assets, templates and included files read from the
+// project filesystem fsys rather than from disk.
+func newConfigFS(b []byte, fsys fs.FS, opts configOptions) (*config, error) {
+	c, err := parseConfigFS(b, false, fsys, "")
+	if err != nil {
+		return nil, err
+	}
+	c.allErrors = opts.allErrors
+	c.strict = opts.strict
+	c.strictTemplates = opts.strictTemplates
+	err = c.validateConfig()
+	return c, err
+}
+
+// parseConfig unmarshals a yaml file, including the pages from any
+// included files, without validation.
+func parseConfig(b []byte, embeddedMode bool) (*config, error) {
+	return parseConfigFS(b, embeddedMode, nil, "")
+}
+
+// parseConfigFS is parseConfig with the included files read from the
+// project filesystem fsys, if not nil, or otherwise from disk relative
+// to baseDir, if set.
+func parseConfigFS(b []byte, embeddedMode bool, fsys fs.FS, baseDir string) (*config, error) {
 	var c config
 	if err := yaml.Unmarshal(b, &c); err != nil {
 		return nil, fmt.Errorf("unmarshal error: %v", err)
 	}
 	c.embeddedMode = embeddedMode
-	err := c.validateConfig()
-	return &c, err
+	c.projectFS = fsys
+	c.baseDir = baseDir
+	if err := c.includePages(); err != nil {
+		return nil, err
+	}
+	return &c, nil
 }
 
+// includePages appends the pages from each included file to the
+// config's pages. Duplicate URLs across files are reported by
+// validateConfig.
+func (c *config) includePages() error {
+	if len(c.Include) > 0 && c.embeddedMode {
+		return ErrInvalidConfig{CodeInclude, "include is not supported in embedded mode"}
+	}
+	for _, inc := range c.Include {
+		b, err := c.readFile(inc)
+		if err != nil {
+			return ErrInvalidConfig{CodeInclude, fmt.Sprintf("include file %q could not be read: %v", inc, err)}
+		}
+		var included struct {
+			Pages []page `yaml:"pages"`
+		}
+		if err := yaml.Unmarshal(b, &included); err != nil {
+			return fmt.Errorf("unmarshal error for include file %q: %v", inc, err)
+		}
+		c.Pages = append(c.Pages, included.Pages...)
+	}
+	return nil
+}
+
+// Zone Viewport values.
+const (
+	viewportAny     = "any"
+	viewportMobile  = "mobile"
+	viewportDesktop = "desktop"
+)
+
+// backTarget is the zone Target which returns to the previous page in
+// the browser history.
+const backTarget = "back"
+
 // pageZone sets up a rectangular page zone on a page that, when
-// clicked, redirects to Target.
+// clicked, redirects to Target. Target is either the URL of a page, an
+// absolute http(s) url of an external site, "back" to return to the
+// previous page in the browser history or a fragment, such as
+// "#details", naming an in-page overlay for the project's scripts to
+// show.
 type pageZone struct {
 	Left   int    `yaml:"Left"`
 	Top    int    `yaml:"Top"`
@@ -224,7 +1202,76 @@ type pageZone struct {
 	Bottom int    `yaml:"Bottom"`
 	Target string `yaml:"Target"`
 
+	// Label is the title used for external Targets, which otherwise
+	// use the Target host.
+	Label string `yaml:"Label,omitempty"`
+
+	// RightTarget and MiddleTarget are optional page URLs for right and
+	// middle mouse button clicks.
+	RightTarget  string `yaml:"RightTarget,omitempty"`
+	MiddleTarget string `yaml:"MiddleTarget,omitempty"`
+
+	// Description is optional hover text for the zone, also provided by
+	// the zones JSON endpoint.
+	Description string `yaml:"Description,omitempty"`
+
+	// Group optionally names a zoneGroup in the config groups whose
+	// properties are the defaults for the zone. Transition is an
+	// optional name for the project's css or scripts to animate the
+	// zone's navigation, provided as a data attribute.
+	Group      string `yaml:"Group,omitempty"`
+	Transition string `yaml:"Transition,omitempty"`
+
+	// Viewport is "mobile" or "desktop" for zones active only on those
+	// viewports, provided as a data attribute for css media queries,
+	// and otherwise "any", the default.
+	Viewport string `yaml:"Viewport,omitempty"`
+
+	// Class is an optional css class, or space separated classes, added
+	// to the zone element after the class of its Group, if any.
+	Class string `yaml:"Class,omitempty"`
+
+	GroupClass string // css class of the Group; determined in processing
+
 	TargetTitle string // determined in processing
+	External    bool   // Target is an external url; determined in processing
+	Back        bool   // Target is "back"; determined in processing
+	Overlay     string // overlay name of a fragment Target; determined in processing
+
+	// Zone position as percentages of the image dimensions, determined
+	// in processing if the image can be decoded.
+	LeftPct   float64
+	TopPct    float64
+	WidthPct  float64
+	HeightPct float64
+}
+
+// zoneGroup are the default properties of the zones in a group.
+type zoneGroup struct {
+	Label      string `yaml:"Label,omitempty"`
+	Transition string `yaml:"Transition,omitempty"`
+	Class      string `yaml:"Class,omitempty"`
+}
+
+// apply returns zo with its unset properties set from the group.
+func (g zoneGroup) apply(zo pageZone) pageZone {
+	if zo.Label == "" {
+		zo.Label = g.Label
+	}
+	if zo.Transition == "" {
+		zo.Transition = g.Transition
+	}
+	zo.GroupClass = g.Class
+	return zo
+}
+
+// setPercentages sets the percentage position fields of the pageZone
+// for an image of the given dimensions.
+func (p *pageZone) setPercentages(imageWidth, imageHeight int) {
+	p.LeftPct = percent(p.Left, imageWidth)
+	p.TopPct = percent(p.Top, imageHeight)
+	p.WidthPct = percent(p.Width(), imageWidth)
+	p.HeightPct = percent(p.Height(), imageHeight)
 }
 
 // Width returns the width of the pageZone.
@@ -237,6 +1284,12 @@ func (p *pageZone) Height() int {
 	return p.Bottom - p.Top
 }
 
+// pageTarget is a zone target of a page and its title.
+type pageTarget struct {
+	Target      string
+	TargetTitle string
+}
+
 // page is a web page represented by an image located at URL, holding 0
 // or more Zones which, when clicked, redirect to the page in question.
 type page struct {
@@ -246,8 +1299,64 @@ type page struct {
 	Note      string     `yaml:"Note,omitempty"`
 	Zones     []pageZone `yaml:"Zones"`
 
+	// Order optionally sets the position of the page in the index and
+	// navigation. Pages without an Order sort after those with one.
+	Order *int `yaml:"Order,omitempty"`
+
+	// Meta is arbitrary key/value metadata (such as author, status or
+	// a ticket link) passed untouched to the template as .Meta.
+	Meta map[string]string `yaml:"Meta,omitempty"`
+
+	// MediaType is "image" (the default) or "video" for an animated
+	// mockup, such as a looping mp4, at ImagePath.
+	MediaType string `yaml:"MediaType,omitempty"`
+
+	// Background is an optional css hex or named color applied to the
+	// page body behind the image, passed to the template as
+	// .Background.
+	Background string `yaml:"Background,omitempty"`
+
+	// Favicon is an optional path to a favicon in the static
+	// directory, such as "static/brand.svg", linked from the page in
+	// place of the global favicon.
+	Favicon string `yaml:"Favicon,omitempty"`
+
+	// Lang optionally overrides the config's content language for the
+	// page.
+	Lang string `yaml:"Lang,omitempty"`
+
+	// AutoAdvance optionally navigates to another page after a delay
+	// without a click, such as from a loading state or splash screen.
+	AutoAdvance *autoAdvance `yaml:"AutoAdvance,omitempty"`
+
+	// Terminal marks an end state page, such as a "Thank you" screen,
+	// which may have no zones.
+	Terminal bool `yaml:"Terminal,omitempty"`
+
 	// Markdown content from Note.
 	NoteHTML template.HTML
+
+	// Targets are the distinct zone targets of the page, determined in
+	// processing.
+	Targets []pageTarget
+
+	// StepIndex (from 1) and StepCount give the position of the page
+	// in a linear walkthrough, determined in processing if the zone
+	// targets form a simple chain, and are otherwise zero.
+	StepIndex int
+	StepCount int
+
+	// Image dimensions, determined in processing if the image can be
+	// decoded.
+	ImageWidth  int
+	ImageHeight int
+}
+
+// autoAdvance is a timed navigation from a page to the page at Target
+// after AfterMs milliseconds.
+type autoAdvance struct {
+	Target  string `yaml:"Target"`
+	AfterMs int    `yaml:"AfterMs"`
 }
 
 // dirExists checks if the path is to a valid directory.
@@ -263,38 +1372,43 @@ func dirExists(path string) bool {
 }
 
 // WriteAssets writes the embedded assets described in the config to
-// disk.
-func WriteAssets(c *config, savePath string) error {
+// disk, returning the paths of the files written, including those
+// written before an error.
+func WriteAssets(c *config, savePath string) ([]string, error) {
 	if !dirExists(savePath) {
-		return fmt.Errorf("directory %s does not exist", savePath)
+		return nil, fmt.Errorf("directory %s does not exist", savePath)
 	}
 	if !c.embeddedMode {
-		return errors.New("write assets only permitted for embedded mode")
+		return nil, errors.New("write assets only permitted for embedded mode")
 	}
 
 	// Check if the target directory or config files exists
 	assetFP := filepath.Join(savePath, AssetDirName)
 	if _, err := os.Stat(assetFP); err == nil {
-		return fmt.Errorf("target directory %q already exists", assetFP)
+		return nil, fmt.Errorf("target directory %q already exists", assetFP)
 	}
 	configFP := filepath.Join(savePath, ConfigFileName)
 	if _, err := os.Stat(configFP); err == nil {
-		return fmt.Errorf("config file %q already exists", configFP)
+		return nil, fmt.Errorf("config file %q already exists", configFP)
 	}
 
 	// For each embedded FS, write its contents to the corresponding
 	// target directory.
-	err := writeFSToDisk(assetFP, c.AssetsFS)
+	written, err := writeFSToDisk(assetFP, c.AssetsFS)
 	if err != nil {
-		return fmt.Errorf("error writing %s: %w", AssetDirName, err)
+		return written, fmt.Errorf("error writing %s: %w", AssetDirName, err)
+	}
+	if err := os.WriteFile(configFP, configYaml, 0644); err != nil {
+		return written, err
 	}
-	return os.WriteFile(configFP, configYaml, 0644)
+	return append(written, configFP), nil
 }
 
 // writeFSToDisk walks an embed.FS and writes its contents to a physical
-// directory on disk.
-func writeFSToDisk(destRoot string, sourceFS fs.FS) error {
-	return fs.WalkDir(sourceFS, ".", func(path string, d fs.DirEntry, err error) error {
+// directory on disk, returning the paths of the files written.
+func writeFSToDisk(destRoot string, sourceFS fs.FS) ([]string, error) {
+	written := []string{}
+	err := fs.WalkDir(sourceFS, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err // propogate errors
 		}
@@ -317,7 +1431,9 @@ func writeFSToDisk(destRoot string, sourceFS fs.FS) error {
 		if err := os.WriteFile(destPath, fileBytes, 0644); err != nil {
 			return fmt.Errorf("could not write file to %s: %w", destPath, err)
 		}
+		written = append(written, destPath)
 
 		return nil
 	})
+	return written, err
 }
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"log"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -69,7 +76,7 @@ pages:
 `},
 		{
 			name: "index template not set",
-			err:  ErrInvalidConfig{"index template not set"},
+			err:  ErrInvalidConfig{CodeTemplateParse, "index template not set"},
 			config: `
 ---
 assetsDir: "assets"
@@ -101,7 +108,7 @@ pages:
 
 		{
 			name: "too few pages",
-			err:  ErrInvalidConfig{"too few pages"},
+			err:  ErrInvalidConfig{CodeTooFewPages, "too few pages"},
 			config: `
 ---
 assetsDir: "assets"
@@ -123,7 +130,7 @@ pages:
 
 		{
 			name: "too few zones",
-			err:  ErrInvalidConfig{"too few zones"},
+			err:  ErrInvalidConfig{CodeNoZones, "too few zones"},
 			config: `
 ---
 assetsDir: "assets"
@@ -146,15 +153,1613 @@ pages:
         Right: 538
         Bottom: 73
         Target: "/home"
+`},
+		{
+			name: "terminal page without zones",
+			err:  nil,
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Terminal: true
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
 `},
 		{
 			name: "invalid zone url",
-			err:  ErrInvalidConfig{"invalid zone url"},
+			err:  ErrInvalidConfig{CodeInvalidTarget, "invalid zone url"},
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/homes"
+`},
+		{
+			name: "favicon not found",
+			err:  ErrInvalidConfig{CodeFaviconNotFound, "favicon not found"},
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+favicon: "static/nonexistent.svg"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`},
+		{
+			name: "inline favicon",
+			err:  nil,
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+favicon: '<svg xmlns="http://www.w3.org/2000/svg"><circle r="4"/></svg>'
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`},
+		{
+			name: "invalid header name",
+			err:  ErrInvalidConfig{CodeInvalidHeader, "invalid header name"},
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+headers:
+  "X Frame Options": "DENY"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`},
+		{
+			name: "invalid allowed origin",
+			err:  ErrInvalidConfig{CodeInvalidOrigin, "invalid allowed origin"},
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+allowedOrigins:
+  - "https://example.com/path"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`},
+		{
+			name: "missing custom dir",
+			err:  ErrInvalidConfig{CodeMissingDirectory, "required directory not found"},
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+dirs:
+  images: "img"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`},
+		{
+			name: "invalid right target",
+			err:  ErrInvalidConfig{CodeInvalidTarget, "invalid right target"},
+			config: `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+        RightTarget: "/menu"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+        MiddleTarget: "/home"
+`},
+		{
+			name: "duplicate url",
+			err:  ErrInvalidConfig{CodeDuplicateURL, "duplicate url"},
 			config: `
 ---
 assetsDir: "assets"
 pageTemplate: "templates/page.html"
 indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/home"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newConfig([]byte(tt.config), embeddedMode)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			var expectedErr ErrInvalidConfig
+			if errors.As(tt.err, &expectedErr) {
+				var actualErr ErrInvalidConfig
+				if !errors.As(err, &actualErr) {
+					t.Fatalf("expected ErrInvalidConfig, got %T (%v)", err, err)
+				}
+				if got, want := actualErr.Code, expectedErr.Code; got != want {
+					t.Errorf("error code got %s want %s (%v)", got, want, err)
+				}
+			}
+		})
+	}
+}
+
+// Test embedded configuration.
+func TestConfigEmbedded(t *testing.T) {
+
+	var embeddedMode = true
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "ok", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newConfig(configYaml, embeddedMode)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			var expectedErr ErrInvalidConfig
+			if errors.As(tt.err, &expectedErr) {
+				var actualErr ErrInvalidConfig
+				if !errors.As(err, &actualErr) {
+					t.Fatalf("expected ErrInvalidConfig, got %T (%v)", err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigTargetTitles(t *testing.T) {
+
+	var embeddedMode = false
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	cfg, err := newConfig([]byte(config), embeddedMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Detail Home " // the reverse of the pages
+	var got strings.Builder
+	for _, p := range cfg.Pages {
+		for _, z := range p.Zones {
+			got.WriteString(fmt.Sprintf("%s ", z.TargetTitle))
+		}
+	}
+	if got.String() != want {
+		t.Errorf("got %s want %s", got.String(), want)
+	}
+}
+
+// TestConfigPageTargets checks that the distinct zone targets of each
+// page are recorded with their titles.
+func TestConfigPageTargets(t *testing.T) {
+	c := &config{Pages: []page{
+		{URL: "/a", Zones: []pageZone{
+			{Target: "/b", TargetTitle: "B"},
+			{Target: "https://example.com", TargetTitle: "example.com"},
+			{Target: "/b", TargetTitle: "B"},
+		}},
+		{URL: "/b", Zones: []pageZone{{Target: "/a", TargetTitle: "A"}}},
+	}}
+	c.setTargets()
+	want := [][]pageTarget{
+		{{Target: "/b", TargetTitle: "B"}, {Target: "https://example.com", TargetTitle: "example.com"}},
+		{{Target: "/a", TargetTitle: "A"}},
+	}
+	for ii, p := range c.Pages {
+		if diff := cmp.Diff(want[ii], p.Targets); diff != "" {
+			t.Errorf("page %s targets mismatch (-want +got):\n%s", p.URL, diff)
+		}
+	}
+}
+
+func TestConfigExternalTargets(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "https://example.com/docs"
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "http://api.example.com"
+        Label: "Live API"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	cfg, err := newConfig([]byte(config), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range cfg.Pages {
+		for _, z := range p.Zones {
+			got = append(got, fmt.Sprintf("%s:%t", z.TargetTitle, z.External))
+		}
+	}
+	want := []string{"example.com:true", "Live API:true", "Home:false"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+
+	// Other schemes are treated as internal targets.
+	_, err = newConfig([]byte(strings.Replace(config, "https://example.com/docs", "ftp://example.com", 1)), false)
+	if err == nil || !strings.Contains(err.Error(), "invalid Zone Target URL") {
+		t.Errorf("expected invalid zone target error, got %v", err)
+	}
+}
+
+// TestConfigBackTargets checks that "back" targets are accepted without
+// a matching page and are omitted from the page targets.
+func TestConfigBackTargets(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "back"
+`
+	cfg, err := newConfig([]byte(config), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zo := cfg.Pages[1].Zones[0]
+	if got, want := fmt.Sprintf("%s:%t", zo.TargetTitle, zo.Back), "Back:true"; got != want {
+		t.Errorf("back zone got %s want %s", got, want)
+	}
+	if got := len(cfg.Pages[1].Targets); got != 0 {
+		t.Errorf("got %d targets want none", got)
+	}
+}
+
+// TestConfigZoneViewport checks that zone viewports default to "any"
+// and are otherwise "mobile" or "desktop".
+func TestConfigZoneViewport(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+        Viewport: "%s"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		viewport string
+		want     string
+		ok       bool
+	}{
+		{"", "any", true},
+		{"any", "any", true},
+		{"mobile", "mobile", true},
+		{"desktop", "desktop", true},
+		{"tablet", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.viewport, func(t *testing.T) {
+			c, err := newConfig(fmt.Appendf(nil, config, tt.viewport), false)
+			if !tt.ok {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidZone {
+					t.Errorf("expected invalid zone error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.Pages[0].Zones[0].Viewport; got != tt.want {
+				t.Errorf("viewport got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigOverlayTargets checks that fragment targets name overlays,
+// titled from the name, without a matching page.
+func TestConfigOverlayTargets(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "%s"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"#details", "details:Details", true},
+		{"#detail-card", "detail-card:Detail card", true},
+		{"#", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			cfg, err := newConfig(fmt.Appendf(nil, config, tt.target), false)
+			if !tt.ok {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidTarget {
+					t.Errorf("expected invalid target error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			zo := cfg.Pages[0].Zones[1]
+			if got := zo.Overlay + ":" + zo.TargetTitle; got != tt.want {
+				t.Errorf("overlay zone got %s want %s", got, tt.want)
+			}
+			if got, want := len(cfg.Pages[0].Targets), 1; got != want {
+				t.Errorf("got %d targets want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestConfigZonePercentages(t *testing.T) {
+
+	config := makeOKConfig(t, false)
+	cfg, err := newConfig([]byte(config), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pg := cfg.Pages[0]
+	if got, want := [2]int{pg.ImageWidth, pg.ImageHeight}, [2]int{800, 560}; got != want {
+		t.Fatalf("image size got %v want %v", got, want)
+	}
+	round := func(f float64) float64 {
+		return math.Round(f*100) / 100
+	}
+	z := pg.Zones[0] // 367, 44, 539, 263
+	got := []float64{round(z.LeftPct), round(z.TopPct), round(z.WidthPct), round(z.HeightPct)}
+	want := []float64{45.88, 7.86, 21.5, 39.11}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConfigInclude(t *testing.T) {
+
+	included := `
+---
+pages:
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	includeFile := filepath.Join(t.TempDir(), "detail.yaml")
+	if err := os.WriteFile(includeFile, []byte(included), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := func(homeURL string) string {
+		return fmt.Sprintf(`
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+include:
+  - %q
+pages:
+  -
+    URL: %q
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+`, includeFile, homeURL)
+	}
+
+	cfg, err := newConfig([]byte(config("/home")), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(cfg.Pages), 2; got != want {
+		t.Fatalf("pages got %d want %d", got, want)
+	}
+	if got, want := cfg.Pages[0].Zones[0].TargetTitle, "Detail"; got != want {
+		t.Errorf("target title got %q want %q", got, want)
+	}
+
+	// duplicate URL across files
+	_, err = newConfig([]byte(config("/detail")), false)
+	var eic ErrInvalidConfig
+	if !errors.As(err, &eic) || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected duplicate url error, got %v", err)
+	}
+}
+
+func TestConfigTemplatesDir(t *testing.T) {
+
+	templatesDir := t.TempDir()
+	for _, f := range []string{"page.html", "index.html"} {
+		b, err := os.ReadFile(filepath.Join("assets", "templates", f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(templatesDir, f), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := func(dir string) string {
+		return fmt.Sprintf(`
+---
+assetsDir: "assets"
+templatesDir: %q
+pageTemplate: "page.html"
+indexTemplate: "index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`, dir)
+	}
+
+	cfg, err := newConfig([]byte(config(templatesDir)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.PageTpl.Name(), "page.html"; got != want {
+		t.Errorf("page template got %q want %q", got, want)
+	}
+
+	_, err = newConfig([]byte(config(filepath.Join(templatesDir, "missing"))), false)
+	if err == nil || !strings.Contains(err.Error(), "templates directory") {
+		t.Errorf("expected missing templates directory error, got %v", err)
+	}
+}
+
+// TestConfigTemplateDelims checks that templates are parsed with the
+// configured delimiters, which must be a pair.
+func TestConfigTemplateDelims(t *testing.T) {
+
+	templatesDir := t.TempDir()
+	for f, content := range map[string]string{
+		"page.html":  `<h1>[[ .Page.Title ]]</h1><p>{{ sample }}</p>`,
+		"index.html": `<ul>[[ range .AllPages ]]<li>[[ .Title ]]</li>[[ end ]]</ul>`,
+	} {
+		if err := os.WriteFile(filepath.Join(templatesDir, f), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := func(delims string) string {
+		return fmt.Sprintf(`
+---
+assetsDir: "assets"
+templatesDir: %q
+templateDelims: %s
+pageTemplate: "page.html"
+indexTemplate: "index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`, templatesDir, delims)
+	}
+
+	cfg, err := newConfig([]byte(config(`["[[", "]]"]`)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := cfg.PageTpl.Execute(&buf, templateData{Page: &cfg.Pages[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `<h1>Home</h1><p>{{ sample }}</p>`; got != want {
+		t.Errorf("page got %q want %q", got, want)
+	}
+
+	for _, delims := range []string{`["[["]`, `["[[", "]]", "!!"]`, `["", "]]"]`} {
+		_, err := newConfig([]byte(config(delims)), false)
+		var eic ErrInvalidConfig
+		if !errors.As(err, &eic) || eic.Code != CodeInvalidDelims {
+			t.Errorf("%s: expected invalid delims error, got %v", delims, err)
+		}
+	}
+}
+
+// TestConfigStrictTemplates checks that templates referencing missing
+// keys only fail with the strictTemplates option.
+func TestConfigStrictTemplates(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html": &fstest.MapFile{Data: []byte(`<p>{{ .Page.Meta.author }}</p>`)},
+	}
+	data := templateData{Page: &page{Meta: map[string]string{"status": "draft"}}}
+
+	for _, strict := range []bool{false, true} {
+		c := &config{TemplatesFS: fsys, strictTemplates: strict}
+		tpl, err := c.parseTemplate("page.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tpl.Execute(io.Discard, data)
+		if got, want := err != nil, strict; got != want {
+			t.Errorf("strict %t got error %v", strict, err)
+		}
+		if strict && err != nil && !strings.Contains(err.Error(), `"author"`) {
+			t.Errorf("error %q does not name the missing key", err)
+		}
+	}
+}
+
+func TestOrderPages(t *testing.T) {
+	order := func(i int) *int { return &i }
+	pages := []page{
+		{URL: "/a"},
+		{URL: "/b", Order: order(2)},
+		{URL: "/c"},
+		{URL: "/d", Order: order(1)},
+		{URL: "/e", Order: order(2)},
+	}
+	var got []string
+	for _, p := range orderPages(pages) {
+		got = append(got, p.URL)
+	}
+	if diff := cmp.Diff(got, []string{"/d", "/b", "/e", "/a", "/c"}); diff != "" {
+		t.Error(diff)
+	}
+	if pages[0].URL != "/a" {
+		t.Error("orderPages modified its input")
+	}
+}
+
+func TestConfigURLPatterns(t *testing.T) {
+
+	config := func(itemURL, target string) string {
+		return fmt.Sprintf(`
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: %q
+  -
+    URL: %q
+    Title: "Item"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`, target, itemURL)
+	}
+
+	tests := []struct {
+		name    string
+		itemURL string
+		target  string
+		wantErr string
+	}{
+		{"literal", "/item/{id}", "/item/{id}", ""},
+		{"matched", "/item/{id}", "/item/42", ""},
+		{"matched regexp", "/item/{id:[0-9]+}", "/item/42", ""},
+		{"not matched regexp", "/item/{id:[0-9]+}", "/item/abc", "invalid Zone Target URL"},
+		{"not matched", "/item/{id}", "/other/42", "invalid Zone Target URL"},
+		{"invalid pattern", "/item/{id", "/home", "invalid URL pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newConfig([]byte(config(tt.itemURL, tt.target)), false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := cfg.Pages[0].Zones[0].TargetTitle, "Item"; got != want {
+				t.Errorf("target title got %q want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestConfigZoneGroups(t *testing.T) {
+
+	config := func(group string) string {
+		return fmt.Sprintf(`
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+groups:
+  back:
+    Label: "Back"
+    Transition: "slide-right"
+    Class: "back-btn"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "https://example.com"
+        Group: %q
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+        Group: %q
+        Transition: "fade"
+`, group, group)
+	}
+
+	cfg, err := newConfig([]byte(config("back")), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	external := cfg.Pages[0].Zones[0]
+	if got, want := external.TargetTitle, "Back"; got != want {
+		t.Errorf("group label title got %q want %q", got, want)
+	}
+	if got, want := external.Transition, "slide-right"; got != want {
+		t.Errorf("group transition got %q want %q", got, want)
+	}
+	if got, want := external.GroupClass, "back-btn"; got != want {
+		t.Errorf("group class got %q want %q", got, want)
+	}
+	if got, want := cfg.Pages[1].Zones[0].Transition, "fade"; got != want {
+		t.Errorf("overridden transition got %q want %q", got, want)
+	}
+
+	_, err = newConfig([]byte(config("missing")), false)
+	var eic ErrInvalidConfig
+	if !errors.As(err, &eic) || eic.Code != CodeUnknownGroup {
+		t.Errorf("expected unknown group error, got %v", err)
+	}
+}
+
+func TestConfigHTMLNotes(t *testing.T) {
+
+	var embeddedMode = false
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Note: "**hi** there [old](./man)"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	cfg, err := newConfig([]byte(config), embeddedMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<p><strong>hi</strong> there <a href="./man">old</a></p>`
+	var got strings.Builder
+	for _, p := range cfg.Pages {
+		if p.NoteHTML != "" {
+			got.WriteString(string(p.NoteHTML))
+		}
+	}
+	if diff := cmp.Diff(strings.TrimSpace(got.String()), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestConfigHTMLNotesAllowHTML checks that raw html in notes is omitted
+// unless notesAllowHTML is set.
+func TestConfigHTMLNotesAllowHTML(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+%s
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Note: "**hi** <script>alert(1)</script> there"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		name       string
+		setting    string
+		wantScript bool
+	}{
+		{"default", "", false},
+		{"disallowed", "notesAllowHTML: false", false},
+		{"allowed", "notesAllowHTML: true", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newConfig(fmt.Appendf(nil, config, tt.setting), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			noteHTML := string(cfg.Pages[0].NoteHTML)
+			if !strings.Contains(noteHTML, "<strong>hi</strong>") {
+				t.Errorf("note missing bold markdown: %s", noteHTML)
+			}
+			if got, want := strings.Contains(noteHTML, "<script>"), tt.wantScript; got != want {
+				t.Errorf("note script got %t want %t: %s", got, want, noteHTML)
+			}
+		})
+	}
+}
+
+// TestConfigPageBackground checks that page backgrounds must be css hex
+// or named colors.
+func TestConfigPageBackground(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Background: "%s"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		background string
+		ok         bool
+	}{
+		{"", true},
+		{"#eee", true},
+		{"#F0F0F0", true},
+		{"#f0f0f080", true},
+		{"whitesmoke", true},
+		{"#eeeee", false},
+		{"#ggg", false},
+		{"red; color: blue", false},
+		{"url(x.png)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.background, func(t *testing.T) {
+			_, err := newConfig(fmt.Appendf(nil, config, tt.background), false)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != CodeInvalidBackground {
+				t.Errorf("expected invalid background error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestConfigPageFavicon checks that a page favicon must be a file in
+// the static directory.
+func TestConfigPageFavicon(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Favicon: "%s"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		favicon string
+		ok      bool
+	}{
+		{"", true},
+		{"static/favicon.svg", true},
+		{"static/missing.svg", false},
+		{"images/home.jpg", false},
+		{"static", false},
+		{"static/../images/home.jpg", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.favicon, func(t *testing.T) {
+			_, err := newConfig(fmt.Appendf(nil, config, tt.favicon), false)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != CodeFaviconNotFound {
+				t.Errorf("expected favicon not found error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestConfigRedirects checks that redirects must map non-page paths
+// to page urls.
+func TestConfigRedirects(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+redirects:
+  "%s": "%s"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		name string
+		from string
+		to   string
+		ok   bool
+	}{
+		{"ok", "/old-home", "/home", true},
+		{"relative path", "old-home", "/home", false},
+		{"page path", "/detail", "/home", false},
+		{"missing target", "/old-home", "/nowhere", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newConfig(fmt.Appendf(nil, config, tt.from, tt.to), false)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != CodeInvalidRedirect {
+				t.Errorf("expected invalid redirect error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestConfigSplashTemplate checks that the splash template is parsed
+// and that no page may also be served at "/".
+func TestConfigSplashTemplate(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+splashTemplate: "%s"
+pages:
+  -
+    URL: "%s"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/detail"
+`
+	tests := []struct {
+		name     string
+		splash   string
+		url      string
+		wantCode ErrorCode
+	}{
+		{"ok", "templates/splash.html", "/home", ""},
+		{"unset", "", "/", ""},
+		{"missing", "templates/nonexistent.html", "/home", CodeTemplateParse},
+		{"page at root", "templates/splash.html", "/", CodeDuplicateURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newConfig(fmt.Appendf(nil, config, tt.splash, tt.url), false)
+			if tt.wantCode != "" {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != tt.wantCode {
+					t.Errorf("expected %s error, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := c.SplashTpl != nil, tt.splash != ""; got != want {
+				t.Errorf("splash template parsed %t want %t", got, want)
+			}
+		})
+	}
+}
+
+// TestConfigErrorTemplate checks that the error template is parsed if
+// set.
+func TestConfigErrorTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantCode ErrorCode
+	}{
+		{"ok", "templates/error.html", ""},
+		{"unset", "", ""},
+		{"missing", "templates/nonexistent.html", CodeTemplateParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := initServerConfig(t)
+			c.ErrorTemplate = tt.template
+			err := c.validateConfig()
+			if tt.wantCode != "" {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != tt.wantCode {
+					t.Errorf("expected %s error, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := c.ErrorTpl != nil, tt.template != ""; got != want {
+				t.Errorf("error template parsed %t want %t", got, want)
+			}
+		})
+	}
+}
+
+// TestConfigAssetsURL checks that the assets url must be an absolute
+// http(s) url, and that a trailing slash is removed.
+func TestConfigAssetsURL(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+assetsURL: "%s"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		assetsURL string
+		want      string
+		ok        bool
+	}{
+		{"", "", true},
+		{"https://cdn.example.com", "https://cdn.example.com", true},
+		{"https://cdn.example.com/demo/", "https://cdn.example.com/demo", true},
+		{"/assets", "", false},
+		{"ftp://cdn.example.com", "", false},
+		{"https://", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assetsURL, func(t *testing.T) {
+			c, err := newConfig(fmt.Appendf(nil, config, tt.assetsURL), false)
+			if !tt.ok {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidAssetsURL {
+					t.Errorf("expected invalid assets url error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.AssetsURL; got != tt.want {
+				t.Errorf("assets url got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigBasePath checks that the base path must be an absolute
+// path and prefixes relative note links.
+func TestConfigBasePath(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+basePath: "%s"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Note: "[old](./man) and [ext](https://example.com)"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		basePath string
+		want     string
+		ok       bool
+	}{
+		{"", `<p><a href="./man">old</a> and <a href="https://example.com">ext</a></p>`, true},
+		{"/proto", `<p><a href="/proto/man">old</a> and <a href="https://example.com">ext</a></p>`, true},
+		{"/proto/", `<p><a href="/proto/man">old</a> and <a href="https://example.com">ext</a></p>`, true},
+		{"proto", "", false},
+		{"/proto/../x", "", false},
+		{"/", `<p><a href="./man">old</a> and <a href="https://example.com">ext</a></p>`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.basePath, func(t *testing.T) {
+			c, err := newConfig(fmt.Appendf(nil, config, tt.basePath), false)
+			if !tt.ok {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidBasePath {
+					t.Errorf("expected invalid base path error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.TrimSpace(string(c.Pages[0].NoteHTML)); got != tt.want {
+				t.Errorf("note got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigStaticAllowedExtensions checks that static extensions are
+// normalised to lower case with a leading dot.
+func TestConfigStaticAllowedExtensions(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+staticAllowedExtensions: %s
 pages:
   -
     URL: "/home"
@@ -177,21 +1782,55 @@ pages:
         Top:  31
         Right: 538
         Bottom: 73
-        Target: "/homes"
-`},
-		{
-			name: "duplicate url",
-			err:  ErrInvalidConfig{"duplicate url"},
-			config: `
+        Target: "/home"
+`
+	tests := []struct {
+		exts string
+		want []string
+		ok   bool
+	}{
+		{"[]", []string{}, true},
+		{`["css", ".JS"]`, []string{".css", ".js"}, true},
+		{`[""]`, nil, false},
+		{`["tar.gz"]`, nil, false},
+		{`["../env"]`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.exts, func(t *testing.T) {
+			c, err := newConfig(fmt.Appendf(nil, config, tt.exts), false)
+			if !tt.ok {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidExtension {
+					t.Errorf("expected invalid extension error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.StaticAllowedExtensions; !slices.Equal(got, tt.want) {
+				t.Errorf("extensions got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigLang checks that the config and page languages must be
+// language tags, with the config language defaulting to "en".
+func TestConfigLang(t *testing.T) {
+
+	config := `
 ---
 assetsDir: "assets"
 pageTemplate: "templates/page.html"
 indexTemplate: "templates/index.html"
+lang: "%s"
 pages:
   -
     URL: "/home"
     Title: "Home"
     ImagePath: "images/home.jpg"
+    Lang: "%s"
     Zones:
       -
         Left:   367
@@ -200,7 +1839,7 @@ pages:
         Bottom: 263
         Target: "/detail"
   -
-    URL: "/home"
+    URL: "/detail"
     Title: "Detail"
     ImagePath: "images/detail.jpg"
     Zones:
@@ -210,76 +1849,84 @@ pages:
         Right: 538
         Bottom: 73
         Target: "/home"
-`},
+`
+	tests := []struct {
+		lang     string
+		pageLang string
+		want     string
+		ok       bool
+	}{
+		{"", "", "en", true},
+		{"pt-BR", "", "pt-BR", true},
+		{"de", "fr", "de", true},
+		{"e", "", "", false},
+		{"en_GB", "", "", false},
+		{"en", "fr<script>", "", false},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			_, err := newConfig([]byte(tt.config), embeddedMode)
-			if tt.err == nil {
-				if err != nil {
-					t.Fatalf("unexpected error: %v", err)
+		t.Run(tt.lang+"/"+tt.pageLang, func(t *testing.T) {
+			c, err := newConfig(fmt.Appendf(nil, config, tt.lang, tt.pageLang), false)
+			if !tt.ok {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidLang {
+					t.Errorf("expected invalid lang error, got %v", err)
 				}
 				return
 			}
-			if err == nil {
-				t.Fatalf("expected an error")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
 			}
-			var expectedErr ErrInvalidConfig
-			if errors.As(tt.err, &expectedErr) {
-				var actualErr ErrInvalidConfig
-				if !errors.As(err, &actualErr) {
-					t.Fatalf("expected ErrInvalidConfig, got %T (%v)", err, err)
-				}
+			if got := c.Lang; got != tt.want {
+				t.Errorf("lang got %q want %q", got, tt.want)
 			}
 		})
 	}
 }
 
-// Test embedded configuration.
-func TestConfigEmbedded(t *testing.T) {
-
-	var embeddedMode = true
-
+// TestConfigAutoAdvance checks that an auto advance must target a page
+// without path variables after a positive delay.
+func TestConfigAutoAdvance(t *testing.T) {
 	tests := []struct {
-		name string
-		err  error
+		name    string
+		advance autoAdvance
+		code    ErrorCode
 	}{
-		{name: "ok", err: nil},
+		{"ok", autoAdvance{Target: "/detail", AfterMs: 1500}, ""},
+		{"missing target", autoAdvance{Target: "/nope", AfterMs: 1500}, CodeInvalidTarget},
+		{"pattern target", autoAdvance{Target: "/item/{id}", AfterMs: 1500}, CodeInvalidTarget},
+		{"no delay", autoAdvance{Target: "/detail"}, CodeInvalidDelay},
+		{"negative delay", autoAdvance{Target: "/detail", AfterMs: -1}, CodeInvalidDelay},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := newConfig(configYaml, embeddedMode)
-			if tt.err == nil {
+			c := initServerConfig(t)
+			c.Pages = append(c.Pages, page{URL: "/item/{id}", Title: "Item", ImagePath: "images/detail.jpg", Zones: c.Pages[1].Zones})
+			c.Pages[0].AutoAdvance = &tt.advance
+			err := c.validateConfig()
+			if tt.code == "" {
 				if err != nil {
 					t.Fatalf("unexpected error: %v", err)
 				}
 				return
 			}
-			if err == nil {
-				t.Fatalf("expected an error")
-			}
-			var expectedErr ErrInvalidConfig
-			if errors.As(tt.err, &expectedErr) {
-				var actualErr ErrInvalidConfig
-				if !errors.As(err, &actualErr) {
-					t.Fatalf("expected ErrInvalidConfig, got %T (%v)", err, err)
-				}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != tt.code || !strings.Contains(err.Error(), "AutoAdvance") {
+				t.Errorf("expected invalid auto advance error, got %v", err)
 			}
 		})
 	}
 }
 
-func TestConfigTargetTitles(t *testing.T) {
-
-	var embeddedMode = false
+// TestConfigTour checks that tour urls must be distinct page urls
+// without path variables.
+func TestConfigTour(t *testing.T) {
 
 	config := `
 ---
 assetsDir: "assets"
 pageTemplate: "templates/page.html"
 indexTemplate: "templates/index.html"
+tour: %s
 pages:
   -
     URL: "/home"
@@ -303,38 +1950,128 @@ pages:
         Right: 538
         Bottom: 73
         Target: "/home"
+  -
+    URL: "/item/{id}"
+    Title: "Item"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
 `
-	cfg, err := newConfig([]byte(config), embeddedMode)
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		tour string
+		ok   bool
+	}{
+		{"[]", true},
+		{`["/detail", "/home"]`, true},
+		{`["/home", "/missing"]`, false},
+		{`["/home", "/detail", "/home"]`, false},
+		{`["/home", "/item/{id}"]`, false},
 	}
-	want := "Detail Home " // the reverse of the pages
-	var got strings.Builder
-	for _, p := range cfg.Pages {
-		for _, z := range p.Zones {
-			got.WriteString(fmt.Sprintf("%s ", z.TargetTitle))
+	for _, tt := range tests {
+		t.Run(tt.tour, func(t *testing.T) {
+			_, err := newConfig(fmt.Appendf(nil, config, tt.tour), false)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != CodeInvalidTour {
+				t.Errorf("expected invalid tour error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestConfigSteps checks that steps are only set for pages forming a
+// linear chain.
+func TestConfigSteps(t *testing.T) {
+	zones := func(targets ...string) []pageZone {
+		zs := []pageZone{}
+		for _, tg := range targets {
+			zs = append(zs, pageZone{Target: tg})
 		}
+		return zs
 	}
-	if got.String() != want {
-		t.Errorf("got %s want %s", got.String(), want)
+	tests := []struct {
+		name  string
+		pages []page
+		want  [][2]int // StepIndex, StepCount for each page
+	}{
+		{
+			name: "linear with back links",
+			pages: []page{
+				{URL: "/a", Zones: zones("/b")},
+				{URL: "/c", Zones: zones("/b", "/a")},
+				{URL: "/b", Zones: zones("/a", "/c")},
+			},
+			want: [][2]int{{1, 3}, {3, 3}, {2, 3}},
+		},
+		{
+			name: "linear with external link",
+			pages: []page{
+				{URL: "/a", Zones: []pageZone{{Target: "/b"}, {Target: "https://example.com", External: true}}},
+				{URL: "/b", Zones: zones("/a")},
+			},
+			want: [][2]int{{1, 2}, {2, 2}},
+		},
+		{
+			name: "branching",
+			pages: []page{
+				{URL: "/a", Zones: zones("/b", "/c")},
+				{URL: "/b", Zones: zones("/a")},
+				{URL: "/c", Zones: zones("/a")},
+			},
+			want: [][2]int{{0, 0}, {0, 0}, {0, 0}},
+		},
+		{
+			name: "unreachable page",
+			pages: []page{
+				{URL: "/a", Zones: zones("/b")},
+				{URL: "/b", Zones: zones("/a")},
+				{URL: "/c", Zones: zones("/a")},
+			},
+			want: [][2]int{{0, 0}, {0, 0}, {0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config{Pages: tt.pages, pagesByURL: map[string]int{}}
+			for ii, p := range c.Pages {
+				c.pagesByURL[p.URL] = ii
+			}
+			c.setSteps()
+			got := [][2]int{}
+			for _, p := range c.Pages {
+				got = append(got, [2]int{p.StepIndex, p.StepCount})
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("steps mismatch (-want +got):\n%s", diff)
+			}
+		})
 	}
 }
 
-func TestConfigHTMLNotes(t *testing.T) {
-
-	var embeddedMode = false
-
+// TestConfigMinTapSize checks that zones smaller than minTapSize are
+// logged as warnings, or reported as errors in strict mode.
+func TestConfigMinTapSize(t *testing.T) {
 	config := `
 ---
 assetsDir: "assets"
 pageTemplate: "templates/page.html"
 indexTemplate: "templates/index.html"
+minTapSize: %d
 pages:
   -
     URL: "/home"
     Title: "Home"
     ImagePath: "images/home.jpg"
-    Note: "**hi** there [old](./man)"
     Zones:
       -
         Left:   367
@@ -354,19 +2091,187 @@ pages:
         Bottom: 73
         Target: "/home"
 `
-	cfg, err := newConfig([]byte(config), embeddedMode)
+	tests := []struct {
+		name        string
+		minTapSize  int
+		strict      bool
+		wantWarning bool
+		wantErr     bool
+	}{
+		{"off", 0, true, false, false},
+		{"large enough", 42, true, false, false},
+		{"warning", 44, false, true, false},
+		{"strict", 44, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			log.SetOutput(&logBuf)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			_, err := newConfigDir(fmt.Appendf(nil, config, tt.minTapSize), ".", configOptions{strict: tt.strict})
+			if got, want := strings.Contains(logBuf.String(), "smaller than the minimum tap size"), tt.wantWarning; got != want {
+				t.Errorf("warning got %t want %t: %q", got, want, logBuf.String())
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != CodeSmallZone {
+				t.Fatalf("expected small zone error, got %v", err)
+			}
+			if got, want := err.Error(), "page Detail (1) zone 0 size 102x42"; !strings.Contains(got, want) {
+				t.Errorf("got %q want error containing %q", got, want)
+			}
+		})
+	}
+}
+
+// TestConfigMediaType checks that the page media type must match the
+// media file extension and that the file must exist.
+func TestConfigMediaType(t *testing.T) {
+	projectFS := fstest.MapFS{
+		"assets/images/anim.mp4": &fstest.MapFile{Data: []byte("not really a video")},
+	}
+	err := fs.WalkDir(os.DirFS("."), AssetDirName, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		projectFS[p] = &fstest.MapFile{Data: b}
+		return err
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := `<p><strong>hi</strong> there <a href="./man">old</a></p>`
-	var got strings.Builder
-	for _, p := range cfg.Pages {
-		if p.NoteHTML != "" {
-			got.WriteString(string(p.NoteHTML))
-		}
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "%s"
+    MediaType: "%s"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	tests := []struct {
+		imagePath string
+		mediaType string
+		want      string // the resulting media type, or empty for an error
+	}{
+		{"images/home.jpg", "", "image"},
+		{"images/home.jpg", "image", "image"},
+		{"images/anim.mp4", "video", "video"},
+		{"images/anim.mp4", "", ""},
+		{"images/anim.mp4", "image", ""},
+		{"images/home.avif", "", "image"},
+		{"images/missing.jpg", "image", "image"},
+		{"images/home.jpg", "video", ""},
+		{"images/missing.mp4", "video", ""},
+		{"images/home.jpg", "audio", ""},
 	}
-	if diff := cmp.Diff(strings.TrimSpace(got.String()), want); diff != "" {
-		t.Error(diff)
+	for _, tt := range tests {
+		t.Run(tt.imagePath+" "+tt.mediaType, func(t *testing.T) {
+			cfg, err := newConfigFS(fmt.Appendf(nil, config, tt.imagePath, tt.mediaType), projectFS, configOptions{})
+			if tt.want == "" {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != CodeInvalidMedia {
+					t.Fatalf("expected invalid media error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cfg.Pages[0].MediaType; got != tt.want {
+				t.Errorf("media type got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigAllErrors checks that all page and zone errors are reported
+// in aggregate mode, while only the first is reported normally.
+func TestConfigAllErrors(t *testing.T) {
+
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: ""
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/nowhere"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 0
+        Bottom: 73
+        Target: "/home"
+`
+	_, err := newConfig([]byte(config), false)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, ok := err.(interface{ Unwrap() []error }); ok {
+		t.Fatalf("expected a single error, got %v", err)
+	}
+	if got, want := err.Error(), "title empty for page 0"; !strings.Contains(got, want) {
+		t.Errorf("got %q want error containing %q", got, want)
+	}
+
+	_, err = newConfigAllErrors([]byte(config), false)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %T", err)
+	}
+	if got, want := len(joined.Unwrap()), 3; got != want {
+		t.Errorf("got %d errors want %d: %v", got, want, err)
+	}
+	var eic ErrInvalidConfig
+	if !errors.As(err, &eic) {
+		t.Error("expected joined error to contain ErrInvalidConfig")
 	}
 }
 
@@ -383,6 +2288,10 @@ func recursiveFSPrinter(t *testing.T, fi fs.FS, addFiles ...string) string {
 		if err != nil {
 			return err
 		}
+		// the embedded examples are not written by WriteAssets
+		if d.IsDir() && path == "examples" {
+			return fs.SkipDir
+		}
 		pathParts := strings.SplitSeq(path, "/")
 		for pp := range pathParts {
 			for _, p := range pathStrings {
@@ -424,10 +2333,13 @@ func TestConfigWriteEmbedded(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = WriteAssets(c, testDir)
+	written, err := WriteAssets(c, testDir)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !slices.Contains(written, filepath.Join(testDir, ConfigFileName)) {
+		t.Errorf("written files %v do not include the config", written)
+	}
 
 	got := recursiveFSPrinter(t, os.DirFS(testDir))
 
@@ -439,7 +2351,7 @@ func TestConfigWriteEmbedded(t *testing.T) {
 
 // TestErrInvalidConfig tests the custom error.
 func TestErrInvalidConfig(t *testing.T) {
-	e := ErrInvalidConfig{"hi"}
+	e := ErrInvalidConfig{CodeTooFewPages, "hi"}
 	var eic ErrInvalidConfig
 	if !errors.As(e, &eic) {
 		t.Fatal("expected ErrInvalidConfig")
@@ -447,4 +2359,7 @@ func TestErrInvalidConfig(t *testing.T) {
 	if got, want := e.Error(), "invalid config or template: hi"; got != want {
 		t.Errorf("error got %s want %s", got, want)
 	}
+	if got, want := eic.Code, CodeTooFewPages; got != want {
+		t.Errorf("code got %s want %s", got, want)
+	}
 }
@@ -0,0 +1,152 @@
+package main
+
+// devOverlay injects an error banner into html pages served in
+// development mode, so that configuration problems are visible in the
+// browser while the last good configuration continues to be served,
+// together with a development-only script.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// devOverlay holds the current development mode error, if any. It is
+// shared between successive servers in development mode.
+type devOverlay struct {
+	mu  sync.Mutex
+	err error
+}
+
+// setError records the current error.
+func (d *devOverlay) setError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
+
+// clearError removes the current error.
+func (d *devOverlay) clearError() {
+	d.setError(nil)
+}
+
+// devScript is the development-only script injected into html pages.
+// It saves the scroll position before the page unloads and restores it
+// after a reload, so that the viewport is kept across reloads of tall
+// images.
+const devScript = `<script id="firstgo-dev-script">
+(function () {
+    var key = "firstgo-scroll:" + location.pathname;
+    window.addEventListener("beforeunload", function () {
+        sessionStorage.setItem(key, String(window.scrollY));
+    });
+    window.addEventListener("load", function () {
+        var nav = performance.getEntriesByType("navigation")[0];
+        var y = sessionStorage.getItem(key);
+        if (nav && nav.type === "reload" && y !== null) {
+            window.scrollTo(0, parseInt(y, 10));
+        }
+    });
+})();
+</script>`
+
+// snippet returns the html to inject into pages: the error banner, if
+// there is an error, followed by the development script. Config
+// validation errors are shown with their code, such as
+// "DUPLICATE_URL", which is also set in the banner's data-code
+// attribute.
+func (d *devOverlay) snippet() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err == nil {
+		return devScript
+	}
+	var code, attr string
+	var eic ErrInvalidConfig
+	if errors.As(d.err, &eic) && eic.Code != "" {
+		code = fmt.Sprintf("[%s] ", eic.Code)
+		attr = fmt.Sprintf(` data-code="%s"`, html.EscapeString(string(eic.Code)))
+	}
+	return fmt.Sprintf(
+		`<div id="firstgo-dev-error"%s style="position: fixed; top: 0; left: 0; right: 0; z-index: 1000; padding: 8px 12px; background-color: #b00020; color: white; font: 11pt monospace; white-space: pre-wrap;">firstgo: the last good configuration is being served until this is fixed: %s%s</div>%s`,
+		attr,
+		html.EscapeString(code),
+		html.EscapeString(d.err.Error()),
+		devScript,
+	)
+}
+
+// middleware injects the overlay snippet before the closing body tag
+// of html responses.
+func (d *devOverlay) middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snippet := d.snippet()
+		bw := &bufferedHTMLWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(bw, r)
+		if !bw.buffering {
+			return
+		}
+		body := injectBeforeBodyClose(bw.buf.Bytes(), snippet)
+		// the body is altered, so any ETag no longer applies
+		w.Header().Del("Content-Length")
+		w.Header().Del("ETag")
+		w.WriteHeader(bw.status)
+		_, _ = w.Write(body)
+	})
+}
+
+// bufferedHTMLWriter buffers html responses so that they can be
+// altered, passing other responses through directly.
+type bufferedHTMLWriter struct {
+	http.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	buf       bytes.Buffer
+}
+
+// decide determines whether the response should be buffered, based on
+// its content type.
+func (b *bufferedHTMLWriter) decide() {
+	if b.decided {
+		return
+	}
+	b.decided = true
+	b.buffering = strings.HasPrefix(b.Header().Get("Content-Type"), "text/html")
+}
+
+// WriteHeader records the status of buffered responses.
+func (b *bufferedHTMLWriter) WriteHeader(status int) {
+	b.decide()
+	if b.buffering {
+		b.status = status
+		return
+	}
+	b.ResponseWriter.WriteHeader(status)
+}
+
+// Write buffers html responses.
+func (b *bufferedHTMLWriter) Write(p []byte) (int, error) {
+	b.decide()
+	if b.buffering {
+		return b.buf.Write(p)
+	}
+	return b.ResponseWriter.Write(p)
+}
+
+// injectBeforeBodyClose inserts snippet before the last closing body
+// tag in body, or appends it if no closing body tag is found.
+func injectBeforeBodyClose(body []byte, snippet string) []byte {
+	idx := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+	if idx < 0 {
+		return append(body, []byte(snippet)...)
+	}
+	out := make([]byte, 0, len(body)+len(snippet))
+	out = append(out, body[:idx]...)
+	out = append(out, snippet...)
+	return append(out, body[idx:]...)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDevOverlay(t *testing.T) {
+
+	htmlHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><p>page</p></body></html>")
+	})
+	cssHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		fmt.Fprint(w, "body {}")
+	})
+
+	tests := []struct {
+		name    string
+		err     error
+		handler http.Handler
+		want    string
+		exact   bool
+	}{
+		{
+			name:    "no error script injected",
+			handler: htmlHandler,
+			want:    "<html><body><p>page</p><script id=\"firstgo-dev-script\">",
+		},
+		{
+			name:    "script preserves scroll",
+			handler: htmlHandler,
+			want:    "sessionStorage.setItem(key, String(window.scrollY))",
+		},
+		{
+			name:    "error injected",
+			err:     errors.New("bad <config>"),
+			handler: htmlHandler,
+			want:    "<html><body><p>page</p><div id=\"firstgo-dev-error\"",
+		},
+		{
+			name:    "error escaped",
+			err:     errors.New("bad <config>"),
+			handler: htmlHandler,
+			want:    "bad &lt;config&gt;</div><script",
+		},
+		{
+			name:    "config error code shown",
+			err:     ErrInvalidConfig{CodeDuplicateURL, "URL for page 1 (/home) already exists"},
+			handler: htmlHandler,
+			want:    "fixed: [DUPLICATE_URL] invalid config or template: URL for page 1 (/home) already exists</div>",
+		},
+		{
+			name:    "config error code attribute",
+			err:     fmt.Errorf("reload: %w", ErrInvalidConfig{CodeNoZones, "no zones"}),
+			handler: htmlHandler,
+			want:    `<div id="firstgo-dev-error" data-code="NO_ZONES" style=`,
+		},
+		{
+			name:    "non html untouched",
+			err:     errors.New("bad config"),
+			handler: cssHandler,
+			want:    "body {}",
+			exact:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlay := &devOverlay{}
+			overlay.setError(tt.err)
+			w := httptest.NewRecorder()
+			overlay.middleware(tt.handler).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			if got := w.Body.String(); !strings.Contains(got, tt.want) {
+				t.Errorf("body %q does not contain %q", got, tt.want)
+			}
+			if tt.exact && w.Body.String() != tt.want {
+				t.Errorf("body altered: %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestInjectBeforeBodyClose(t *testing.T) {
+	if got, want := string(injectBeforeBodyClose([]byte("<p>x</p>"), "<i>")), "<p>x</p><i>"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+	if got, want := string(injectBeforeBodyClose([]byte("<BODY>x</BODY>"), "<i>")), "<BODY>x<i></BODY>"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+// etag provides ETags for rendered pages so that conditional requests
+// for unchanged pages are answered with 304 Not Modified.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// etagFor returns a strong ETag derived from the content b.
+func etagFor(b []byte) string {
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports if the If-None-Match header value ifNoneMatch
+// matches etag, using the weak comparison required for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for tag := range strings.SplitSeq(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// write writes the rendered html b to w. If ETags are enabled an ETag
+// is set and 304 Not Modified is returned without the content if it
+// matches the request's If-None-Match header.
+func (s *server) write(w http.ResponseWriter, r *http.Request, b []byte) {
+	if s.options.ETag {
+		etag := etagFor(b)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	_, _ = w.Write(b)
+}
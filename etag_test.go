@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := etagFor([]byte("<html></html>"))
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{"", false},
+		{etag, true},
+		{"W/" + etag, true},
+		{`"other", ` + etag, true},
+		{`"other"`, false},
+		{"*", true},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("%q got %t want %t", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
+
+// TestServerETag checks that pages and the index are served with an
+// ETag and that matching conditional requests receive a 304.
+func TestServerETag(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		s := initServer(t)
+		s.options.ETag = enabled
+
+		handler, err := s.buildHandler()
+		if err != nil {
+			t.Fatal("buildHander error:", err)
+		}
+
+		for _, url := range []string{"/home", "/index"} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+			etag := w.Header().Get("ETag")
+			if got, want := etag != "", enabled; got != want {
+				t.Fatalf("%s etag enabled %t got etag %q", url, enabled, etag)
+			}
+			if !enabled {
+				continue
+			}
+
+			r := httptest.NewRequest("GET", url, nil)
+			r.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if got, want := w.Code, http.StatusNotModified; got != want {
+				t.Errorf("%s status got %d want %d", url, got, want)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("%s unexpected body for 304: %q", url, w.Body.String())
+			}
+
+			r = httptest.NewRequest("GET", url, nil)
+			r.Header.Set("If-None-Match", `"stale"`)
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if got, want := w.Code, http.StatusOK; got != want {
+				t.Errorf("%s stale status got %d want %d", url, got, want)
+			}
+		}
+	}
+}
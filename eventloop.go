@@ -7,6 +7,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"runtime"
 )
 
 // Msg is the result of an IO operation.
@@ -30,8 +32,15 @@ type EventLoop struct {
 	startCmd   Cmd
 	cmdMap     map[Msg]Cmd
 	msgChan    chan Msg
+	trace      TraceFunc
+	cmdNames   map[uintptr]string
 }
 
+// TraceFunc is called on each dispatch with the name of the command
+// that produced msg, msg itself and the name of the next command to be
+// run.
+type TraceFunc func(state string, msg Msg, next string)
+
 // NewEventLoop registers a new eventloop with a set of labelled
 // commands, a command to start the event loop process, and a default
 // command to run when a message does not match any label.
@@ -48,6 +57,7 @@ func NewEventLoop(cmds []LabelledCmd, startCmd, defaultCmd Cmd) (*EventLoop, err
 		startCmd:   startCmd,
 		defaultCmd: defaultCmd,
 		msgChan:    make(chan Msg),
+		cmdNames:   map[uintptr]string{},
 	}
 	for _, lc := range cmds {
 		if _, found := e.cmdMap[lc.label]; found {
@@ -67,6 +77,29 @@ func (e *EventLoop) Update(msg Msg) Cmd {
 	return e.defaultCmd
 }
 
+// SetTrace registers a TraceFunc to be called on each dispatch.
+func (e *EventLoop) SetTrace(trace TraceFunc) {
+	e.trace = trace
+}
+
+// NameCmd registers a name for cmd used in tracing. Unnamed commands are
+// traced using their function name.
+func (e *EventLoop) NameCmd(name string, cmd Cmd) {
+	e.cmdNames[reflect.ValueOf(cmd).Pointer()] = name
+}
+
+// cmdName returns the registered or function name of cmd.
+func (e *EventLoop) cmdName(cmd Cmd) string {
+	ptr := reflect.ValueOf(cmd).Pointer()
+	if name, ok := e.cmdNames[ptr]; ok {
+		return name
+	}
+	if f := runtime.FuncForPC(ptr); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
+
 // Run execute commands and pipes the result back to msgChan.
 func (e *EventLoop) Run(ctx context.Context) {
 
@@ -83,11 +116,17 @@ func (e *EventLoop) Run(ctx context.Context) {
 		}()
 	}
 	// Start initial process.
-	doCmd(e.startCmd)
+	current := e.startCmd
+	doCmd(current)
 
-	// The main event loop only ever receives from the channel.
+	// The main event loop only ever receives from the channel. As only
+	// one command runs at a time, the current command produced msg.
 	for msg := range e.msgChan {
 		cmd := e.Update(msg)
+		if e.trace != nil {
+			e.trace(e.cmdName(current), msg, e.cmdName(cmd))
+		}
+		current = cmd
 		doCmd(cmd)
 	}
 }
@@ -71,3 +71,52 @@ func TestEventLoop(t *testing.T) {
 	}
 	el.Run(ctx)
 }
+
+func TestEventLoopTrace(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startCmd := func(ctx context.Context) Msg {
+		return "A"
+	}
+	bCmd := func(ctx context.Context) Msg {
+		return "B"
+	}
+	stopCmd := func(ctx context.Context) Msg {
+		cancel()
+		<-ctx.Done()
+		return "STOPPED"
+	}
+
+	el, err := NewEventLoop(
+		[]LabelledCmd{
+			LabelledCmd{"A", bCmd},
+			LabelledCmd{"B", stopCmd},
+		},
+		startCmd,
+		stopCmd,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el.NameCmd("start", startCmd)
+	el.NameCmd("b", bCmd)
+	el.NameCmd("stop", stopCmd)
+
+	var traces []string
+	el.SetTrace(func(state string, msg Msg, next string) {
+		traces = append(traces, fmt.Sprintf("[%s] %s -> %s", state, msg, next))
+	})
+	el.Run(ctx)
+
+	want := []string{"[start] A -> b", "[b] B -> stop"}
+	if len(traces) < len(want) {
+		t.Fatalf("got traces %v want at least %v", traces, want)
+	}
+	for i, w := range want {
+		if traces[i] != w {
+			t.Errorf("trace %d got %q want %q", i, traces[i], w)
+		}
+	}
+}
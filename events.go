@@ -0,0 +1,56 @@
+package main
+
+// devEvents writes a JSON-lines stream of development mode state
+// changes, such as config loads, file updates and errors, so that
+// editors and other tools can report reload status without scraping the
+// log.
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// devEvent is a single line of the development mode event stream.
+type devEvent struct {
+	Event   string   `json:"event"`
+	Files   []string `json:"files,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// devEvents encodes devEvent lines to w. A nil devEvents, or one with a
+// nil writer, discards events.
+type devEvents struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// newDevEvents returns a devEvents writing to w, which may be nil.
+func newDevEvents(w io.Writer) *devEvents {
+	d := &devEvents{}
+	if w != nil {
+		d.enc = json.NewEncoder(w)
+	}
+	return d
+}
+
+// emit writes the event named event, with the optional changed files.
+func (d *devEvents) emit(event string, files ...string) {
+	d.write(devEvent{Event: event, Files: files})
+}
+
+// error writes an error event for err.
+func (d *devEvents) error(err error) {
+	d.write(devEvent{Event: "error", Message: err.Error()})
+}
+
+// write encodes e as a single line. Write errors are ignored, as the
+// event stream is advisory.
+func (d *devEvents) write(e devEvent) {
+	if d == nil || d.enc == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_ = d.enc.Encode(e)
+}
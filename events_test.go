@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDevEvents(t *testing.T) {
+	var buf bytes.Buffer
+	d := newDevEvents(&buf)
+	d.emit("config_ok")
+	d.emit("reload", "a.yaml", "b.html")
+	d.error(errors.New("bad \"zone\""))
+
+	want := `{"event":"config_ok"}
+{"event":"reload","files":["a.yaml","b.html"]}
+{"event":"error","message":"bad \"zone\""}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDevEventsDiscard(t *testing.T) {
+	var d *devEvents
+	d.emit("config_ok") // nil devEvents should not panic
+	newDevEvents(nil).error(errors.New("discarded"))
+}
@@ -0,0 +1,45 @@
+package main
+
+// examples provides additional embedded example projects, each a
+// directory under examples holding a config file and its assets, that
+// can be served by the demo command in place of the default demo.
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+//go:embed examples
+var examplesFS embed.FS
+
+// exampleNames returns the names of the embedded examples.
+func exampleNames() []string {
+	entries, _ := fs.ReadDir(examplesFS, "examples")
+	names := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// newExampleConfig creates and validates the config of the embedded
+// example name, with its assets read from the example's directory.
+func newExampleConfig(name string) (*config, error) {
+	if name == "" || !fs.ValidPath(name) || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid example name %q", name)
+	}
+	fsys, err := fs.Sub(examplesFS, path.Join("examples", name))
+	if err != nil {
+		return nil, err
+	}
+	b, err := fs.ReadFile(fsys, ConfigFileName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown example %q (available: %s)", name, strings.Join(exampleNames(), ", "))
+	}
+	return newConfigFS(b, fsys, configOptions{})
+}
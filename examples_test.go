@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExampleNames(t *testing.T) {
+	if !slices.Contains(exampleNames(), "mobile") {
+		t.Errorf("expected the mobile example, got %v", exampleNames())
+	}
+}
+
+func TestNewExampleConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		errContains string
+	}{
+		{"mobile", ""},
+		{"missing", "unknown example"},
+		{"../assets", "invalid example name"},
+		{"mobile/assets", "invalid example name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newExampleConfig(tt.name)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("got err %v want err with %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := cfg.Pages[0].URL, "/list"; got != want {
+				t.Errorf("first page got %q want %q", got, want)
+			}
+			if _, err := newServer("127.0.0.1", "8000", cfg, ServerOptions{Precompile: true}); err != nil {
+				t.Errorf("server error: %v", err)
+			}
+		})
+	}
+}
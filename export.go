@@ -0,0 +1,218 @@
+package main
+
+// export writes a prototype as static html files, with its images and
+// static files, so that it can be hosted on any static host, such as
+// GitHub Pages, or opened from disk. Page and index links are rewritten
+// to relative links to the exported ".html" files, and asset links to
+// relative links to the copied assets.
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// exportLinkRe matches the attributes of rendered html holding page or
+// asset urls, including the zone, tour and auto advance targets
+// followed by zones.js.
+var exportLinkRe = regexp.MustCompile(`((?:href|src|data-right-target|data-middle-target|data-tour-prev|data-tour-next|data-auto-advance)=")([^"]*)(")`)
+
+// exportFile returns the path of the html file exported for the page
+// url u, such as "detail.html" for "/detail" and "index.html" for "/".
+func exportFile(u string) string {
+	p := strings.TrimPrefix(u, "/")
+	if p == "" || strings.HasSuffix(p, "/") {
+		return p + "index.html"
+	}
+	return p + ".html"
+}
+
+// exporter writes the pages of a server as static files.
+type exporter struct {
+	s       *server
+	out     string
+	files   map[string]string // exported html file by url
+	skipped []*mux.Route      // pages with path variables, not exported
+}
+
+// exportSite renders the pages, indexes and splash page of s to html
+// files in the directory out, and copies the images and static
+// directories to it unless they are served from an external assets
+// url. The slash separated paths written are returned. Pages with path
+// variables cannot be exported and are skipped with a warning.
+func exportSite(s *server, out string) ([]string, error) {
+	e := &exporter{s: s, out: out, files: map[string]string{}}
+
+	// The index is exported as index.html. The "/index" index is moved
+	// aside if a page or the splash page is at "/".
+	var pages []*page
+	for i := range s.pages {
+		p := &s.pages[i]
+		if isURLPattern(p.URL) {
+			log.Printf("export warning: skipping page %s with path variables", p.URL)
+			e.skipped = append(e.skipped, mux.NewRouter().Path(p.URL))
+			continue
+		}
+		pages = append(pages, p)
+		e.files[p.URL] = exportFile(p.URL)
+	}
+	if s.splashTpl != nil {
+		e.files["/"] = exportFile("/")
+	}
+	for _, idx := range s.indexPages {
+		file := exportFile(idx)
+		if idx != "/" && e.files["/"] == file {
+			file = exportFile(idx + "/")
+		}
+		e.files[idx] = file
+	}
+
+	var written []string
+	write := func(u string, tpl *template.Template, data templateData) error {
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("%s: export render error: %w", u, err)
+		}
+		file := e.files[u]
+		if err := e.writeFile(file, e.rewrite(buf.Bytes(), u, file)); err != nil {
+			return err
+		}
+		written = append(written, file)
+		return nil
+	}
+	for _, p := range pages {
+		if err := write(p.URL, s.pageTpl, s.pageData(p)); err != nil {
+			return written, err
+		}
+	}
+	for _, idx := range s.indexPages {
+		if err := write(idx, s.indexTpl, s.indexData(s.orderedPages)); err != nil {
+			return written, err
+		}
+	}
+	if s.splashTpl != nil {
+		if err := write("/", s.splashTpl, s.splashData()); err != nil {
+			return written, err
+		}
+	}
+
+	if s.assetsURL != "" {
+		return written, nil
+	}
+	for _, d := range []struct {
+		dir, urlPath string
+		exts         []string
+	}{
+		{s.imageDir, s.imagePath, nil},
+		{s.staticDir, s.staticPath, s.staticExts},
+	} {
+		copied, err := e.copyDir(d.dir, d.urlPath, d.exts)
+		written = append(written, copied...)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// rewrite rewrites the page and asset links in the html b, rendered
+// for the page url u, to links relative to its exported file. Links
+// to pages which are not exported are reported with a warning.
+// Links to external urls and to other paths are left untouched.
+func (e *exporter) rewrite(b []byte, u, file string) []byte {
+	base := &url.URL{Path: u}
+	return exportLinkRe.ReplaceAllFunc(b, func(m []byte) []byte {
+		parts := exportLinkRe.FindSubmatch(m)
+		ref, err := url.Parse(string(parts[2]))
+		if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Path == "" {
+			return m
+		}
+		target := base.ResolveReference(ref).Path
+		to, ok := e.files[target]
+		if !ok {
+			if !strings.HasPrefix(target, e.s.imagePath) && !strings.HasPrefix(target, e.s.staticPath) {
+				if e.isSkipped(target) {
+					log.Printf("export warning: %s links to %s, which is not exported", u, target)
+				}
+				return m
+			}
+			to = strings.TrimPrefix(target, "/")
+		}
+		rel, err := filepath.Rel(filepath.Dir(filepath.FromSlash(file)), filepath.FromSlash(to))
+		if err != nil {
+			return m
+		}
+		link := filepath.ToSlash(rel)
+		if ref.Fragment != "" {
+			link += "#" + ref.Fragment
+		}
+		return fmt.Appendf(nil, "%s%s%s", parts[1], link, parts[3])
+	})
+}
+
+// isSkipped reports if the url u is that of a page which was skipped
+// as it has path variables.
+func (e *exporter) isSkipped(u string) bool {
+	req := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: u}}
+	for _, route := range e.skipped {
+		if route.Match(req, &mux.RouteMatch{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyDir copies the assets directory dir to the output directory at
+// its url path, such as "/images/". If exts is set only files with
+// those extensions are copied, as only those are served.
+func (e *exporter) copyDir(dir, urlPath string, exts []string) ([]string, error) {
+	sub, err := fs.Sub(e.s.assetsFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("export %s mount error: %w", dir, err)
+	}
+	prefix := strings.Trim(urlPath, "/")
+	var copied []string
+	err = fs.WalkDir(sub, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if len(exts) > 0 && !extensionAllowed(exts, name) {
+			return nil
+		}
+		content, err := fs.ReadFile(sub, name)
+		if err != nil {
+			return fmt.Errorf("export read error: %w", err)
+		}
+		file := path.Join(prefix, name)
+		if err := e.writeFile(file, content); err != nil {
+			return err
+		}
+		copied = append(copied, file)
+		return nil
+	})
+	return copied, err
+}
+
+// writeFile writes content to the slash separated path file in the
+// output directory.
+func (e *exporter) writeFile(file string, content []byte) error {
+	dst := filepath.Join(e.out, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("export directory error: %w", err)
+	}
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		return fmt.Errorf("export write error: %w", err)
+	}
+	return nil
+}
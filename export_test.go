@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestExportFile(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"/", "index.html"},
+		{"/index", "index.html"},
+		{"/detail", "detail.html"},
+		{"/shop/basket", "shop/basket.html"},
+		{"/shop/", "shop/index.html"},
+	}
+	for _, tt := range tests {
+		if got := exportFile(tt.url); got != tt.want {
+			t.Errorf("exportFile(%q) got %q want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// TestExportSite checks that the pages, index and assets are exported
+// with links rewritten relative to each exported file.
+func TestExportSite(t *testing.T) {
+	cfg := initServerConfig(t)
+	s, err := newServer("127.0.0.1", "0", cfg, ServerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	written, err := exportSite(s, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"home.html", "detail.html", "index.html", "images/home.jpg", "static/styles.css"} {
+		if !slices.Contains(written, want) {
+			t.Errorf("%s not written", want)
+		}
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(want))); err != nil {
+			t.Errorf("%s not found: %v", want, err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(out, "home.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := string(content)
+	for _, want := range []string{
+		`href="detail.html"`,
+		`href="index.html"`,
+		`src="images/home.jpg"`,
+		`href="static/styles.css"`,
+		`src="static/zones.js"`,
+	} {
+		if !strings.Contains(home, want) {
+			t.Errorf("home.html does not contain %s", want)
+		}
+	}
+	if strings.Contains(home, `href="/`) {
+		t.Error("home.html contains an absolute link")
+	}
+}
+
+// TestExportStaticAllowedExtensions checks that only static files with
+// an allowed extension are exported.
+func TestExportStaticAllowedExtensions(t *testing.T) {
+	cfg := initServerConfig(t)
+	cfg.StaticAllowedExtensions = []string{".css", ".js"}
+	s, err := newServer("127.0.0.1", "0", cfg, ServerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := exportSite(s, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"static/styles.css", "static/zones.js", "images/home.jpg"} {
+		if !slices.Contains(written, want) {
+			t.Errorf("%s not written", want)
+		}
+	}
+	if slices.Contains(written, "static/favicon.svg") {
+		t.Error("static/favicon.svg unexpectedly written")
+	}
+}
+
+// TestExportRewrite checks the rewriting of links for a nested page.
+func TestExportRewrite(t *testing.T) {
+	s := &server{imagePath: "/images/", staticPath: "/static/"}
+	e := &exporter{s: s, files: map[string]string{
+		"/":            "index.html",
+		"/detail":      "detail.html",
+		"/shop/basket": "shop/basket.html",
+	}}
+	in := `<a href="/detail#top"></a><a href="/"></a><img src="/images/a.jpg"><a href="https://example.com/"></a><a href="/unknown"></a><a data-right-target="/detail"></a><body data-tour-prev="/" data-tour-next="/detail" data-auto-advance="/detail" data-auto-advance-after="1500">`
+	want := `<a href="../detail.html#top"></a><a href="../index.html"></a><img src="../images/a.jpg"><a href="https://example.com/"></a><a href="/unknown"></a><a data-right-target="../detail.html"></a><body data-tour-prev="../index.html" data-tour-next="../detail.html" data-auto-advance="../detail.html" data-auto-advance-after="1500">`
+	if got := string(e.rewrite([]byte(in), "/shop/basket", "shop/basket.html")); got != want {
+		t.Errorf("rewrite got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestExportRewriteSkipped checks that links to pages which were not
+// exported are left untouched with a warning.
+func TestExportRewriteSkipped(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	s := &server{imagePath: "/images/", staticPath: "/static/"}
+	e := &exporter{
+		s:       s,
+		files:   map[string]string{"/detail": "detail.html"},
+		skipped: []*mux.Route{mux.NewRouter().Path("/item/{id}")},
+	}
+	in := `<a href="/detail"></a><a href="/item/42"></a><a href="/unknown"></a>`
+	want := `<a href="detail.html"></a><a href="/item/42"></a><a href="/unknown"></a>`
+	if got := string(e.rewrite([]byte(in), "/detail", "detail.html")); got != want {
+		t.Errorf("rewrite got\n%s\nwant\n%s", got, want)
+	}
+	if got := logBuf.String(); !strings.Contains(got, "/detail links to /item/42, which is not exported") || strings.Contains(got, "/unknown") {
+		t.Errorf("unexpected warnings: %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+// favicon provides an optional favicon.ico generated by rasterizing the
+// svg favicon. Rasterization requires an iconRasterizer, which is only
+// compiled in with the "svgico" build tag to keep the core lean. When
+// no rasterizer is available the svg favicon is served instead.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// faviconICOSize is the width and height of the generated icon.
+const faviconICOSize = 32
+
+// iconRasterizer rasterizes svg content to a square png image of the
+// given size.
+type iconRasterizer interface {
+	RasterizePNG(svg []byte, size int) ([]byte, error)
+}
+
+// faviconRasterizer is the rasterizer used for favicon.ico generation,
+// set by build-tagged implementations. If nil the svg is served.
+var faviconRasterizer iconRasterizer
+
+// faviconPath returns the path in the assets filesystem of the
+// configured favicon, by default favicon.svg in the static directory.
+func (s *server) faviconPath() string {
+	if s.favicon == "" {
+		return path.Join(s.staticDir, "favicon.svg")
+	}
+	return s.favicon
+}
+
+// faviconSVG returns the svg content of the configured favicon.
+func (s *server) faviconSVG() ([]byte, error) {
+	if isInlineSVG(s.favicon) {
+		return []byte(s.favicon), nil
+	}
+	favicon := s.faviconPath()
+	if !strings.HasSuffix(strings.ToLower(favicon), ".svg") {
+		return nil, fmt.Errorf("favicon %q is not an svg", favicon)
+	}
+	return fs.ReadFile(s.assetsFS, favicon)
+}
+
+// buildFaviconICO rasterizes the svg favicon into ico bytes.
+func (s *server) buildFaviconICO(r iconRasterizer) ([]byte, error) {
+	svg, err := s.faviconSVG()
+	if err != nil {
+		return nil, err
+	}
+	png, err := r.RasterizePNG(svg, faviconICOSize)
+	if err != nil {
+		return nil, fmt.Errorf("favicon rasterization error: %w", err)
+	}
+	return encodeICO(png, faviconICOSize), nil
+}
+
+// FaviconICO serves an ico favicon rasterized from the svg favicon,
+// falling back to serving the svg favicon if no rasterizer is
+// available or rasterization fails.
+func (s *server) FaviconICO(w http.ResponseWriter, r *http.Request) {
+	if s.faviconICO == nil {
+		s.Favicon(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/x-icon")
+	_, _ = w.Write(s.faviconICO)
+}
+
+// encodeICO wraps png image data of the given size in an ico
+// container, which has been supported for png payloads since Windows
+// Vista.
+func encodeICO(png []byte, size int) []byte {
+	var buf bytes.Buffer
+	dim := byte(size)
+	if size >= 256 {
+		dim = 0 // 0 means 256 pixels
+	}
+	// ICONDIR header: reserved, type (1 is icon), image count.
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 1})
+	// ICONDIRENTRY: width, height, palette count, reserved, colour
+	// planes, bits per pixel, data size and data offset.
+	buf.Write([]byte{dim, dim, 0, 0})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+	_ = binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(png)), 6 + 16})
+	buf.Write(png)
+	return buf.Bytes()
+}
+
+// initFaviconICO builds the ico favicon if a rasterizer is available,
+// logging a warning if this fails.
+func (s *server) initFaviconICO() {
+	if faviconRasterizer == nil {
+		return
+	}
+	ico, err := s.buildFaviconICO(faviconRasterizer)
+	if err != nil {
+		log.Printf("favicon.ico unavailable, serving svg: %v", err)
+		return
+	}
+	s.faviconICO = ico
+}
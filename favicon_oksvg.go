@@ -0,0 +1,40 @@
+//go:build svgico
+
+package main
+
+// favicon_oksvg provides an iconRasterizer using oksvg, compiled in
+// with the "svgico" build tag.
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+func init() {
+	faviconRasterizer = oksvgRasterizer{}
+}
+
+// oksvgRasterizer rasterizes svg content with oksvg.
+type oksvgRasterizer struct{}
+
+// RasterizePNG rasterizes svg to a square png of the given size.
+func (o oksvgRasterizer) RasterizePNG(svg []byte, size int) ([]byte, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(svg))
+	if err != nil {
+		return nil, err
+	}
+	icon.SetTarget(0, 0, float64(size), float64(size))
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubRasterizer is an iconRasterizer for testing.
+type stubRasterizer struct {
+	err error
+}
+
+func (s stubRasterizer) RasterizePNG(svg []byte, size int) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []byte("PNG"), nil
+}
+
+func TestFaviconICO(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		rasterizer      iconRasterizer
+		wantContentType string
+		wantBodyPrefix  []byte
+	}{
+		{
+			name:            "no rasterizer falls back to svg",
+			rasterizer:      nil,
+			wantContentType: "image/svg+xml",
+			wantBodyPrefix:  []byte("<svg"),
+		},
+		{
+			name:            "rasterizer failure falls back to svg",
+			rasterizer:      stubRasterizer{err: errors.New("fail")},
+			wantContentType: "image/svg+xml",
+			wantBodyPrefix:  []byte("<svg"),
+		},
+		{
+			name:            "rasterized ico",
+			rasterizer:      stubRasterizer{},
+			wantContentType: "image/x-icon",
+			wantBodyPrefix:  []byte{0, 0, 1, 0, 1, 0, 32, 32},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := faviconRasterizer
+			faviconRasterizer = tt.rasterizer
+			t.Cleanup(func() { faviconRasterizer = orig })
+
+			s := initServer(t)
+			w := httptest.NewRecorder()
+			s.FaviconICO(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+
+			if got, want := w.Header().Get("Content-Type"), tt.wantContentType; !strings.HasPrefix(got, want) {
+				t.Errorf("content type got %q want %q", got, want)
+			}
+			if got := w.Body.Bytes(); !bytes.HasPrefix(got, tt.wantBodyPrefix) {
+				t.Errorf("body prefix got %q want %q", got[:min(len(got), 8)], tt.wantBodyPrefix)
+			}
+		})
+	}
+}
+
+func TestEncodeICO(t *testing.T) {
+	ico := encodeICO([]byte("PNG"), 32)
+	if got, want := len(ico), 22+3; got != want {
+		t.Fatalf("ico length got %d want %d", got, want)
+	}
+	if !bytes.HasSuffix(ico, []byte("PNG")) {
+		t.Error("ico does not end with png data")
+	}
+	// data offset
+	if got, want := ico[18], byte(22); got != want {
+		t.Errorf("data offset got %d want %d", got, want)
+	}
+}
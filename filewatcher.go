@@ -1,7 +1,8 @@
 package main
 
 // fileWatcher watches for writes to files in the specified directories
-// having the configured suffixes.
+// having the configured suffixes or matching the configured glob
+// patterns.
 
 import (
 	"context"
@@ -9,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,7 +22,8 @@ import (
 const defaultFlushDuration time.Duration = 25 * time.Millisecond
 
 // DirFilesDescriptor is a combination of a directory and files with the
-// specified suffixes to watch under it.
+// specified suffixes to watch under it. Suffixes may also be glob
+// patterns, such as "*.tmpl.html", matched against file basenames.
 type DirFilesDescriptor struct {
 	Dir          string
 	FileSuffixes []string
@@ -32,14 +35,15 @@ type FileChangeNotifier struct {
 	dirFiles         []DirFilesDescriptor
 	dirDescriptorMap map[string][]string
 	watcher          *fsnotify.Watcher
-	update           chan bool
+	update           chan []string
 	flushDuration    time.Duration
 }
 
 // NewFileChangeNotifier registers a FileChangeNotifier,
 //
-// Note that suffixes provided without the leading "dot" ('.') have this
-// prepended to the provided suffix.
+// Note that suffixes are converted to glob patterns: a suffix such as
+// "html" or ".html" matches "*.html", while a suffix containing glob
+// metacharacters, such as "*.tmpl.html", is used as a pattern as is.
 //
 // Refer to
 // https://github.com/fsnotify/fsnotify/blob/v1.8.0/cmd/fsnotify/file.go
@@ -52,7 +56,7 @@ func NewFileChangeNotifier(descriptors []DirFilesDescriptor) (*FileChangeNotifie
 	fcn := FileChangeNotifier{
 		dirFiles:         descriptors,
 		dirDescriptorMap: map[string][]string{},
-		update:           make(chan bool),
+		update:           make(chan []string),
 		flushDuration:    defaultFlushDuration,
 	}
 
@@ -79,13 +83,14 @@ func NewFileChangeNotifier(descriptors []DirFilesDescriptor) (*FileChangeNotifie
 			return nil, fmt.Errorf("fsnotify add error for dir %q: %w", dir, err)
 		}
 
-		// add the suffixes, prepending "." if necessary.
+		// add the suffixes as glob patterns.
 		fcn.dirDescriptorMap[dir] = []string{}
 		for _, ix := range desc.FileSuffixes {
-			if len(ix) > 0 && ix[0] != byte('.') {
-				ix = string('.') + ix
+			pattern := suffixPattern(ix)
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid suffix pattern %q: %w", ix, err)
 			}
-			fcn.dirDescriptorMap[dir] = append(fcn.dirDescriptorMap[dir], ix)
+			fcn.dirDescriptorMap[dir] = append(fcn.dirDescriptorMap[dir], pattern)
 		}
 	}
 	return &fcn, nil
@@ -97,11 +102,12 @@ func NewFileChangeNotifier(descriptors []DirFilesDescriptor) (*FileChangeNotifie
 //
 // Watch watches the specified directories for write events for files
 // with the specified suffixes. Consumers should iterate over [Update]
-// to receive notice of a file write event requiring a refresh.
+// to receive notice of a file write event requiring a refresh, together
+// with the names of the files written.
 func (fcn *FileChangeNotifier) Watch(ctx context.Context) error {
 
 	// eventChan is an internal chan used for buffering editor writes.
-	eventChan := make(chan bool)
+	eventChan := make(chan string)
 
 	g, ctx := errgroup.WithContext(ctx)
 
@@ -135,15 +141,13 @@ func (fcn *FileChangeNotifier) Watch(ctx context.Context) error {
 					continue
 				}
 
-				// check the suffixes for this directory
-				suffixes, ok := fcn.dirDescriptorMap[dir]
+				// check the suffix patterns for this directory
+				patterns, ok := fcn.dirDescriptorMap[dir]
 				if !ok {
 					return fmt.Errorf("could not find matcher for dir %q", dir)
 				}
-				for _, ix := range suffixes {
-					if strings.HasSuffix(strings.ToLower(basename), strings.ToLower(ix)) {
-						eventChan <- true
-					}
+				if matchesPattern(basename, patterns) {
+					eventChan <- e.Name
 				}
 			}
 		}
@@ -153,7 +157,7 @@ func (fcn *FileChangeNotifier) Watch(ctx context.Context) error {
 	// goroutine will exit if the context is Done or eventChan is
 	// closed.
 	g.Go(func() error {
-		flush := false
+		files := []string{}
 		timer := time.NewTicker(fcn.flushDuration)
 		for {
 			select {
@@ -162,16 +166,19 @@ func (fcn *FileChangeNotifier) Watch(ctx context.Context) error {
 
 			// Stack writes in the same flushDuration, giving time for
 			// the writes to complete.
-			case _, ok := <-eventChan:
+			case name, ok := <-eventChan:
 				if !ok {
 					return nil
 				}
-				flush = true
+				if !slices.Contains(files, name) {
+					files = append(files, name)
+				}
 				timer.Reset(fcn.flushDuration)
 			case <-timer.C:
-				if flush {
-					fcn.update <- true
-					flush = false
+				if len(files) > 0 {
+					slices.Sort(files)
+					fcn.update <- files
+					files = []string{}
 				}
 			}
 		}
@@ -184,7 +191,29 @@ func (fcn *FileChangeNotifier) Watch(ctx context.Context) error {
 	return err
 }
 
-// Update returns a channel signalling a file refresh event.
-func (fcn *FileChangeNotifier) Update() <-chan bool {
+// suffixPattern converts the suffix ix to a glob pattern. Suffixes
+// without glob metacharacters, such as "html" or ".html", are converted
+// to "*.html".
+func suffixPattern(ix string) string {
+	if strings.ContainsAny(ix, "*?[") {
+		return ix
+	}
+	return "*." + strings.TrimPrefix(ix, ".")
+}
+
+// matchesPattern reports if basename matches any of the glob patterns,
+// ignoring case.
+func matchesPattern(basename string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(basename)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Update returns a channel signalling a file refresh event with the
+// sorted names of the files written since the last event.
+func (fcn *FileChangeNotifier) Update() <-chan []string {
 	return fcn.update
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -71,7 +72,10 @@ func TestFileChangeCanonical(t *testing.T) {
 		if err != nil && !errors.Is(err, context.Canceled) {
 			t.Fatal(err)
 		}
-	case <-fcn.Update():
+	case files := <-fcn.Update():
+		if got, want := files, []string{filepath.Join(dir1, "abc.html")}; !slices.Equal(got, want) {
+			t.Errorf("update files got %v want %v", got, want)
+		}
 		testOK = true
 		cancel()
 		break
@@ -140,3 +144,57 @@ func TestFileChangeNotifier(t *testing.T) {
 		t.Errorf("counter got %d want %d", got, want)
 	}
 }
+
+func TestSuffixPattern(t *testing.T) {
+	tests := []struct {
+		suffix string
+		want   string
+	}{
+		{"html", "*.html"},
+		{".html", "*.html"},
+		{"*.tmpl.html", "*.tmpl.html"},
+		{"page?.gohtml", "page?.gohtml"},
+	}
+	for _, tt := range tests {
+		if got := suffixPattern(tt.suffix); got != tt.want {
+			t.Errorf("suffix %q got %q want %q", tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		basename string
+		suffixes []string
+		want     bool
+	}{
+		{"index.html", []string{"html"}, true},
+		{"index.HTML", []string{".html"}, true},
+		{"indexhtml", []string{"html"}, false},
+		{"page.tmpl.html", []string{"*.tmpl.html"}, true},
+		{"page.html", []string{"*.tmpl.html"}, false},
+		{"page.gohtml", []string{"*.tmpl.html", "gohtml"}, true},
+		{"page1.txt", []string{"page?.txt"}, true},
+		{"page10.txt", []string{"page?.txt"}, false},
+	}
+	for _, tt := range tests {
+		patterns := []string{}
+		for _, s := range tt.suffixes {
+			patterns = append(patterns, suffixPattern(s))
+		}
+		if got := matchesPattern(tt.basename, patterns); got != tt.want {
+			t.Errorf("%q with %v got %t want %t", tt.basename, tt.suffixes, got, tt.want)
+		}
+	}
+}
+
+func TestFileChangeInvalidPattern(t *testing.T) {
+	_, err := NewFileChangeNotifier(
+		[]DirFilesDescriptor{
+			DirFilesDescriptor{t.TempDir(), []string{"[.html"}},
+		},
+	)
+	if err == nil {
+		t.Fatal("expected an invalid pattern error")
+	}
+}
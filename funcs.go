@@ -0,0 +1,72 @@
+package main
+
+// funcs provides the function map available to the page and index
+// templates, which can be extended by library users.
+
+import (
+	"fmt"
+	"html/template"
+	"maps"
+	"strings"
+	"time"
+)
+
+// templateFuncs are the functions available to templates when they
+// are parsed.
+var templateFuncs = defaultFuncMap()
+
+// defaultFuncMap returns the default template functions:
+//
+//	upper, lower          change the case of a string
+//	formatDate            reformat a "2006-01-02" or RFC3339 date string
+//	pct                   an int as a percentage of a total
+//	zoneStyle             the css position and size of a zone
+func defaultFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"formatDate": formatDate,
+		"pct":        percent,
+		"zoneStyle":  zoneStyle,
+	}
+}
+
+// SetTemplateFuncs adds funcs to the functions available to templates,
+// replacing any default functions of the same name. It must be called
+// before the config is created, as the functions are bound to the
+// templates when they are parsed.
+func SetTemplateFuncs(funcs template.FuncMap) {
+	maps.Copy(templateFuncs, funcs)
+}
+
+// formatDate formats the date string value, in "2006-01-02" or RFC3339
+// format, with layout, such as "2 Jan 2006". Values that cannot be
+// parsed are returned unchanged.
+func formatDate(layout, value string) string {
+	for _, in := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(in, value); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return value
+}
+
+// percent returns v as a percentage of total, or 0 if total is 0.
+func percent(v, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) / float64(total) * 100
+}
+
+// zoneStyle returns the css position and size of zone z, as
+// percentages of the image if its dimensions are known, otherwise in
+// pixels.
+func zoneStyle(z pageZone) template.CSS {
+	if z.WidthPct > 0 {
+		return template.CSS(fmt.Sprintf("left: %.4g%%; top: %.4g%%; width: %.4g%%; height: %.4g%%;",
+			z.LeftPct, z.TopPct, z.WidthPct, z.HeightPct))
+	}
+	return template.CSS(fmt.Sprintf("left: %dpx; top: %dpx; width: %dpx; height: %dpx;",
+		z.Left, z.Top, z.Width(), z.Height()))
+}
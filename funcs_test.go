@@ -0,0 +1,69 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		layout, value, want string
+	}{
+		{"2 Jan 2006", "2026-10-14", "14 Oct 2026"},
+		{"2 Jan 2006", "2026-10-14T09:30:00Z", "14 Oct 2026"},
+		{"2 Jan 2006", "next week", "next week"},
+	}
+	for _, tt := range tests {
+		if got := formatDate(tt.layout, tt.value); got != tt.want {
+			t.Errorf("formatDate(%q, %q) got %q want %q", tt.layout, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestZoneStyle(t *testing.T) {
+	z := pageZone{Left: 10, Top: 20, Right: 60, Bottom: 45}
+	if got, want := zoneStyle(z), template.CSS("left: 10px; top: 20px; width: 50px; height: 25px;"); got != want {
+		t.Errorf("pixel style got %q want %q", got, want)
+	}
+	z.setPercentages(200, 100)
+	if got, want := zoneStyle(z), template.CSS("left: 5%; top: 20%; width: 25%; height: 25%;"); got != want {
+		t.Errorf("percentage style got %q want %q", got, want)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	orig := templateFuncs
+	t.Cleanup(func() { templateFuncs = orig })
+	templateFuncs = defaultFuncMap()
+	SetTemplateFuncs(template.FuncMap{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+	})
+
+	tpl, err := template.New("t").Funcs(templateFuncs).Parse(
+		`{{ upper "a" }} {{ lower "B" }} {{ pct 1 4 }} {{ shout "hi" }}`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := tpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "A b 25 HI!"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+// TestConfigTemplateFuncs checks that the configured templates are
+// parsed with the template functions.
+func TestConfigTemplateFuncs(t *testing.T) {
+	cfg := initServerConfig(t)
+	tpl, err := cfg.parseTemplate(cfg.PageTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpl.New("extra").Parse(`{{ zoneStyle (index .Page.Zones 0) }}`); err != nil {
+		t.Errorf("template funcs not available: %v", err)
+	}
+}
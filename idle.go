@@ -0,0 +1,67 @@
+package main
+
+// idle shuts down a server when no requests have arrived within a
+// window, for disposable demo environments.
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// idleTracker records the time of the last request.
+type idleTracker struct {
+	window   time.Duration
+	last     atomic.Int64 // unix nanoseconds
+	shutdown atomic.Bool  // set if the server was shut down when idle
+}
+
+// newIdleTracker returns an idleTracker for the idle window, starting
+// from now.
+func newIdleTracker(window time.Duration) *idleTracker {
+	it := &idleTracker{window: window}
+	it.touch()
+	return it
+}
+
+// touch records a request at the current time.
+func (it *idleTracker) touch() {
+	it.last.Store(time.Now().UnixNano())
+}
+
+// remaining returns the time left in the idle window.
+func (it *idleTracker) remaining() time.Duration {
+	return it.window - time.Since(time.Unix(0, it.last.Load()))
+}
+
+// middleware records the time of each request.
+func (it *idleTracker) middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		it.touch()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// watch shuts down webServer once no requests have arrived within the
+// idle window, or returns when done is closed.
+func (it *idleTracker) watch(webServer *http.Server, done <-chan struct{}) {
+	timer := time.NewTimer(it.window)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+		if remaining := it.remaining(); remaining > 0 {
+			timer.Reset(remaining)
+			continue
+		}
+		log.Printf("no requests for %s: shutting down", it.window)
+		it.shutdown.Store(true)
+		_ = webServer.Shutdown(context.Background())
+		return
+	}
+}
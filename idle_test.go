@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdleTracker(t *testing.T) {
+	it := newIdleTracker(time.Hour)
+	it.last.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+	if it.remaining() > 0 {
+		t.Error("expected the idle window to have passed")
+	}
+
+	w := httptest.NewRecorder()
+	it.middleware(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if it.remaining() <= 0 {
+		t.Error("expected a request to restart the idle window")
+	}
+}
+
+func TestServerIdleShutdown(t *testing.T) {
+	cfg := initServerConfig(t)
+	s, err := newServer("127.0.0.1", "0", cfg, ServerOptions{IdleShutdown: 20 * time.Millisecond, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- Serve(s)
+	}()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Errorf("expected a clean idle shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server not shut down when idle")
+	}
+	if !s.idle.shutdown.Load() {
+		t.Error("expected idle shutdown to be recorded")
+	}
+}
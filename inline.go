@@ -0,0 +1,91 @@
+package main
+
+// inline renders a page as standalone html with its image, stylesheets
+// and scripts inlined from the assets filesystem, so that the result
+// can be shared (for example by email) and rendered offline.
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	inlineStylesheetRe = regexp.MustCompile(`<link[^>]*rel="stylesheet"[^>]*href="([^"]+)"[^>]*>`)
+	inlineScriptRe     = regexp.MustCompile(`<script[^>]*src="([^"]+)"[^>]*></script>`)
+	inlineImageRe      = regexp.MustCompile(`(<img[^>]*src=")([^"]+)(")`)
+	styleTagRe         = regexp.MustCompile(`(?i)</?style[^>]*>`)
+)
+
+// assetPath converts a local url path in rendered html to a path in
+// the assets filesystem, reporting false for external urls.
+func assetPath(src string) (string, bool) {
+	if isExternalURL(src) || strings.HasPrefix(src, "data:") {
+		return "", false
+	}
+	p := strings.TrimPrefix(path.Clean("/"+src), "/")
+	if !fs.ValidPath(p) {
+		return "", false
+	}
+	return p, true
+}
+
+// inlineHTML replaces references to local stylesheets, scripts and
+// images in the rendered html b with their content read from fsys.
+// References to external urls or to files not found in fsys are left
+// untouched.
+func inlineHTML(b []byte, fsys fs.FS) []byte {
+
+	readAsset := func(src string) ([]byte, bool) {
+		p, ok := assetPath(src)
+		if !ok {
+			return nil, false
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, false
+		}
+		return content, true
+	}
+
+	b = inlineStylesheetRe.ReplaceAllFunc(b, func(m []byte) []byte {
+		href := string(inlineStylesheetRe.FindSubmatch(m)[1])
+		content, ok := readAsset(href)
+		if !ok {
+			return m
+		}
+		// stylesheets may be wrapped in style tags of their own
+		content = styleTagRe.ReplaceAll(content, nil)
+		return fmt.Appendf(nil, "<style>\n%s\n</style>", content)
+	})
+
+	b = inlineScriptRe.ReplaceAllFunc(b, func(m []byte) []byte {
+		src := string(inlineScriptRe.FindSubmatch(m)[1])
+		content, ok := readAsset(src)
+		if !ok {
+			return m
+		}
+		return fmt.Appendf(nil, "<script>\n%s\n</script>", content)
+	})
+
+	b = inlineImageRe.ReplaceAllFunc(b, func(m []byte) []byte {
+		parts := inlineImageRe.FindSubmatch(m)
+		src := string(parts[2])
+		content, ok := readAsset(src)
+		if !ok {
+			return m
+		}
+		mediaType := mime.TypeByExtension(path.Ext(src))
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		return fmt.Appendf(nil, "%sdata:%s;base64,%s%s",
+			parts[1], mediaType, base64.StdEncoding.EncodeToString(content), parts[3])
+	})
+
+	return b
+}
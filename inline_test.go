@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInlineHTML(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/styles.css": &fstest.MapFile{Data: []byte("<style>\nbody { color: red; }\n</style>")},
+		"static/zones.js":   &fstest.MapFile{Data: []byte("let x = 1;")},
+		"images/home.png":   &fstest.MapFile{Data: []byte("abc")},
+	}
+
+	input := `<link rel="stylesheet" href="/static/styles.css" />
+<link rel="stylesheet" href="https://example.com/remote.css" />
+<img src="images/home.png" />
+<img src="images/missing.png" />
+<script src="/static/zones.js"></script>`
+
+	want := `<style>
+
+body { color: red; }
+
+</style>
+<link rel="stylesheet" href="https://example.com/remote.css" />
+<img src="data:image/png;base64,YWJj" />
+<img src="images/missing.png" />
+<script>
+let x = 1;
+</script>`
+
+	if got := string(inlineHTML([]byte(input), fsys)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestServerPageInline(t *testing.T) {
+	s := initServer(t)
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/home?inline=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"data:image/jpeg;base64,", ".clickable-zone", "<style>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("inline page missing %q", want)
+		}
+	}
+	for _, notWant := range []string{`href="/static/styles.css"`, `src="/static/zones.js"`} {
+		if strings.Contains(string(body), notWant) {
+			t.Errorf("inline page unexpectedly contains %q", notWant)
+		}
+	}
+}
+
+// TestServerPageInlineAssetsURL checks that assets are inlined from the
+// assets filesystem rather than referenced from the assetsURL.
+func TestServerPageInlineAssetsURL(t *testing.T) {
+	s := initServer(t)
+	s.assetsURL = "https://cdn.example.com"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/home?inline=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "data:image/jpeg;base64,"; !strings.Contains(string(body), want) {
+		t.Errorf("inline page missing %q", want)
+	}
+	if notWant := s.assetsURL; strings.Contains(string(body), notWant) {
+		t.Errorf("inline page unexpectedly contains %q", notWant)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+// notelinks renders page notes with their relative links and images
+// prefixed with the configured base path, for prototypes served under a
+// subpath by a reverse proxy.
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// renderNote renders the markdown note of the page at pageURL with
+// renderer, prefixing the relative link and image destinations with
+// basePath, if set.
+func renderNote(renderer goldmark.Markdown, note, pageURL, basePath string) ([]byte, error) {
+	src := []byte(note)
+	doc := renderer.Parser().Parse(text.NewReader(src))
+	if basePath != "" {
+		err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			switch n := n.(type) {
+			case *ast.Link:
+				n.Destination = []byte(basePathLink(basePath, pageURL, string(n.Destination)))
+			case *ast.Image:
+				n.Destination = []byte(basePathLink(basePath, pageURL, string(n.Destination)))
+			}
+			return ast.WalkContinue, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if err := renderer.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// basePathLink resolves the link dest, such as "./man" or "/man",
+// against the url of its page, pageURL, and prefixes it with basePath,
+// giving "/proto/man" for a basePath of "/proto" on the page "/home",
+// and "/proto/a/man" for "./man" on the page "/a/b". Absolute urls,
+// such as "https://example.com" or "mailto:x@example.com", and fragment
+// or query only links are returned unchanged.
+func basePathLink(basePath, pageURL, dest string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return dest
+	}
+	u = (&url.URL{Path: path.Clean("/" + pageURL)}).ResolveReference(u)
+	p := path.Join(basePath, u.Path)
+	if strings.HasSuffix(u.Path, "/") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	u.Path = p
+	return u.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBasePathLink(t *testing.T) {
+	tests := []struct {
+		page string
+		dest string
+		want string
+	}{
+		{"/home", "./man", "/proto/man"},
+		{"/home", "man", "/proto/man"},
+		{"/home", "/man", "/proto/man"},
+		{"/home", "../man", "/proto/man"},
+		{"/home", "/docs/", "/proto/docs/"},
+		{"/home", "/man?x=1#top", "/proto/man?x=1#top"},
+		{"/home", "images/home.jpg", "/proto/images/home.jpg"},
+		{"/a/b", "./man", "/proto/a/man"},
+		{"/a/b", "man/", "/proto/a/man/"},
+		{"/a/b", "../man", "/proto/man"},
+		{"/a/b", "/man", "/proto/man"},
+		{"/home", "https://example.com/man", "https://example.com/man"},
+		{"/home", "//example.com/man", "//example.com/man"},
+		{"/home", "mailto:someone@example.com", "mailto:someone@example.com"},
+		{"/home", "#top", "#top"},
+		{"/home", "?x=1", "?x=1"},
+	}
+	for _, tt := range tests {
+		if got := basePathLink("/proto", tt.page, tt.dest); got != tt.want {
+			t.Errorf("%s %q got %q want %q", tt.page, tt.dest, got, tt.want)
+		}
+	}
+}
+
+func TestRenderNote(t *testing.T) {
+	note := "See [old](./man), [ext](https://example.com) and ![img](images/home.jpg)."
+
+	got, err := renderNote(md, note, "/home", "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<a href="/proto/man">old</a>`,
+		`<a href="https://example.com">ext</a>`,
+		`<img src="/proto/images/home.jpg" alt="img">`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("%s does not contain %q", got, want)
+		}
+	}
+
+	got, err = renderNote(md, note, "/home", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<a href="./man">old</a>`; !strings.Contains(string(got), want) {
+		t.Errorf("%s does not contain %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+// overlay merges an environment specific yaml config, such as
+// config.local.yaml, over a base config before it is parsed, so that
+// settings can differ between environments without duplicating pages.
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+)
+
+// mergeConfigOverlay returns the yaml config base with overlay merged
+// over it. Scalar and list values in overlay replace those in base and
+// mappings are merged recursively, except for pages, which are merged
+// by URL: an overlay page with the URL of a base page is merged over
+// it, and other overlay pages are appended. Overlay keys which are not
+// config fields, such as a misspelt setting or "port", which is a
+// command line flag, are reported as errors rather than ignored.
+func mergeConfigOverlay(base, overlay []byte) ([]byte, error) {
+	var b, o map[string]any
+	if err := yaml.Unmarshal(base, &b); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %v", err)
+	}
+	if err := yaml.Unmarshal(overlay, &o); err != nil {
+		return nil, fmt.Errorf("overlay unmarshal error: %v", err)
+	}
+	if b == nil {
+		b = map[string]any{}
+	}
+	for k, ov := range o {
+		if k == "pages" {
+			pages, err := mergePages(b[k], ov)
+			if err != nil {
+				return nil, err
+			}
+			b[k] = pages
+			continue
+		}
+		b[k] = mergeValue(b[k], ov)
+	}
+	var fields config
+	if err := yaml.UnmarshalWithOptions(overlay, &fields, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("overlay field error: %v", err)
+	}
+	return yaml.Marshal(b)
+}
+
+// mergeValue merges overlay value ov over base value bv, merging
+// mappings recursively and otherwise replacing bv.
+func mergeValue(bv, ov any) any {
+	bm, bok := bv.(map[string]any)
+	om, ook := ov.(map[string]any)
+	if !bok || !ook {
+		return ov
+	}
+	for k, v := range om {
+		bm[k] = mergeValue(bm[k], v)
+	}
+	return bm
+}
+
+// mergePages merges the overlay pages ov over the base pages bv by URL.
+func mergePages(bv, ov any) ([]any, error) {
+	basePages, _ := bv.([]any)
+	overlayPages, ok := ov.([]any)
+	if !ok {
+		return nil, fmt.Errorf("overlay pages must be a list")
+	}
+	byURL := map[any]int{}
+	for i, p := range basePages {
+		if pm, ok := p.(map[string]any); ok {
+			byURL[pm["URL"]] = i
+		}
+	}
+	for _, p := range overlayPages {
+		pm, ok := p.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("overlay page %v is not a mapping", p)
+		}
+		if i, ok := byURL[pm["URL"]]; ok && pm["URL"] != nil {
+			basePages[i] = mergeValue(basePages[i], pm)
+			continue
+		}
+		basePages = append(basePages, pm)
+	}
+	return basePages, nil
+}
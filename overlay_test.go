@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeConfigOverlay(t *testing.T) {
+
+	base := `
+---
+assetsDir: "assets"
+minTapSize: 20
+headers:
+  X-Frame-Options: "DENY"
+pages:
+  -
+    URL: "/home"
+    Title: "Home"
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/detail"
+  -
+    URL: "/detail"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+`
+	overlay := `
+minTapSize: 44
+headers:
+  Content-Security-Policy: "frame-ancestors 'self'"
+pages:
+  -
+    URL: "/home"
+    Title: "Local home"
+  -
+    URL: "/local"
+    Title: "Local"
+    ImagePath: "images/local.jpg"
+`
+	b, err := mergeConfigOverlay([]byte(base), []byte(overlay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := parseConfig(b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := c.AssetsDir, "assets"; got != want {
+		t.Errorf("assetsDir got %q want %q", got, want)
+	}
+	if got, want := c.MinTapSize, 44; got != want {
+		t.Errorf("minTapSize got %d want %d", got, want)
+	}
+	wantHeaders := map[string]string{
+		"X-Frame-Options":         "DENY",
+		"Content-Security-Policy": "frame-ancestors 'self'",
+	}
+	if diff := cmp.Diff(wantHeaders, c.Headers); diff != "" {
+		t.Errorf("headers mismatch (-want +got):\n%s", diff)
+	}
+	var got []string
+	for _, p := range c.Pages {
+		got = append(got, p.URL+":"+p.Title+":"+p.ImagePath)
+	}
+	want := []string{"/home:Local home:images/home.jpg", "/detail:Detail:images/detail.jpg", "/local:Local:images/local.jpg"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("pages mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := len(c.Pages[0].Zones), 1; got != want {
+		t.Errorf("merged page got %d zones want %d", got, want)
+	}
+
+	_, err = mergeConfigOverlay([]byte(base), []byte("pages: 1\n"))
+	if err == nil || !strings.Contains(err.Error(), "must be a list") {
+		t.Errorf("expected pages list error, got %v", err)
+	}
+
+	for _, unknown := range []string{"port: 8080\n", "siteTitle: Local\n", "pages:\n  - URL: /home\n    Titel: Home\n"} {
+		_, err = mergeConfigOverlay([]byte(base), []byte(unknown))
+		if err == nil || !strings.Contains(err.Error(), "overlay field error") {
+			t.Errorf("%q: expected overlay field error, got %v", unknown, err)
+		}
+	}
+}
+
+func TestLoadServeConfigOverlay(t *testing.T) {
+	configFile := makeOKConfig(t, true)
+	defer func() { _ = os.Remove(configFile) }()
+	overlayFile := writeConfig(t, []byte("pages:\n  - URL: \"/home\"\n    Title: \"Local home\"\n"))
+	defer func() { _ = os.Remove(overlayFile) }()
+
+	c, err := loadServeConfig(configFile, ServerOptions{Overlay: overlayFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Pages[0].Title, "Local home"; got != want {
+		t.Errorf("title got %q want %q", got, want)
+	}
+
+	_, err = loadServeConfig(configFile, ServerOptions{Overlay: "nonexistent.yaml"})
+	if err == nil || !strings.Contains(err.Error(), "overlay") {
+		t.Errorf("expected overlay error, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+// pdf is a minimal PDF storyboardWriter, drawing each page image with
+// a title band above it and zone rectangles with target labels over
+// it. Jpeg images are embedded as they are and other images as
+// compressed RGB. Labels use the standard Helvetica font, so only
+// Latin-1 text is drawn, other characters being replaced with "?".
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// pdfTitleHeight is the height in points of the title band above each
+// page image, which is drawn at one point per pixel.
+const pdfTitleHeight = 28
+
+// pdfWriter writes a storyboard as a PDF to w on Close.
+type pdfWriter struct {
+	w       io.Writer
+	objects [][]byte // by object number less one
+	pages   []int    // page object numbers
+
+	// The current page, if any.
+	content  *bytes.Buffer
+	pageDict string // page dictionary entries other than the contents
+	height   int    // image height
+}
+
+// Object numbers of the fixed objects.
+const (
+	pdfCatalogObj = 1
+	pdfPagesObj   = 2
+	pdfFontObj    = 3
+)
+
+// newPDFWriter returns a pdfWriter writing to w.
+func newPDFWriter(w io.Writer) *pdfWriter {
+	p := &pdfWriter{w: w}
+	p.objects = make([][]byte, 3)
+	p.objects[pdfFontObj-1] = []byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	return p
+}
+
+// addObject adds an object, returning its number.
+func (p *pdfWriter) addObject(b []byte) int {
+	p.objects = append(p.objects, b)
+	return len(p.objects)
+}
+
+// pdfStream returns a stream object with dict entries and data.
+func pdfStream(dict string, data []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	b.Write(data)
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+// Page starts a new page, finishing any current one.
+func (p *pdfWriter) Page(title string, width, height int, img []byte, format string) error {
+	p.finishPage()
+	p.content = &bytes.Buffer{}
+	p.height = height
+
+	resources := fmt.Sprintf("/Font << /F1 %d 0 R >>", pdfFontObj)
+	if format != "" {
+		imgObj, err := p.imageObject(img, format)
+		if err != nil {
+			return err
+		}
+		resources += fmt.Sprintf(" /XObject << /Im1 %d 0 R >>", imgObj)
+		fmt.Fprintf(p.content, "q %d 0 0 %d 0 0 cm /Im1 Do Q\n", width, height)
+	} else {
+		fmt.Fprintf(p.content, "0.9 g 0 0 %d %d re f\n", width, height)
+	}
+	fmt.Fprintf(p.content, "0 g BT /F1 12 Tf 8 %d Td (%s) Tj ET\n", height+10, pdfText(title))
+	p.pageDict = fmt.Sprintf("/Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << %s >>",
+		pdfPagesObj, width, height+pdfTitleHeight, resources,
+	)
+	return nil
+}
+
+// imageObject adds the image img as an XObject, returning its number.
+func (p *pdfWriter) imageObject(img []byte, format string) (int, error) {
+	// Embed RGB and gray jpegs directly; others, such as CMYK jpegs,
+	// are converted.
+	if format == "jpeg" {
+		ic, _, err := image.DecodeConfig(bytes.NewReader(img))
+		space := ""
+		switch {
+		case err != nil:
+		case ic.ColorModel == color.YCbCrModel:
+			space = "/DeviceRGB"
+		case ic.ColorModel == color.GrayModel:
+			space = "/DeviceGray"
+		}
+		if space != "" {
+			return p.addObject(pdfStream(fmt.Sprintf(
+				"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+				ic.Width, ic.Height, space,
+			), img)), nil
+		}
+	}
+	m, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return 0, fmt.Errorf("pdf image error: %w", err)
+	}
+	var raw bytes.Buffer
+	zw := zlib.NewWriter(&raw)
+	b := m.Bounds()
+	row := make([]byte, 0, b.Dx()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row = row[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := m.At(x, y).RGBA()
+			row = append(row, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+		_, _ = zw.Write(row)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("pdf image compression error: %w", err)
+	}
+	return p.addObject(pdfStream(fmt.Sprintf(
+		"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode",
+		b.Dx(), b.Dy(),
+	), raw.Bytes())), nil
+}
+
+// Zone draws a red zone rectangle with its label inside the top left
+// corner. Image coordinates run down from the top left, and PDF
+// coordinates up from the bottom left.
+func (p *pdfWriter) Zone(left, top, right, bottom int, label string) {
+	if p.content == nil {
+		return
+	}
+	y := p.height - bottom
+	fmt.Fprintf(p.content, "0.86 0.1 0.1 RG 2 w %d %d %d %d re S\n", left, y, right-left, bottom-top)
+	fmt.Fprintf(p.content, "0.86 0.1 0.1 rg BT /F1 10 Tf %d %d Td (%s) Tj ET\n", left+3, p.height-top-12, pdfText(label))
+}
+
+// finishPage adds the content stream of the current page, if any, and
+// completes its page object.
+func (p *pdfWriter) finishPage() {
+	if p.content == nil {
+		return
+	}
+	contentObj := p.addObject(pdfStream("", p.content.Bytes()))
+	p.pages = append(p.pages, p.addObject(fmt.Appendf(nil, "<< %s /Contents %d 0 R >>", p.pageDict, contentObj)))
+	p.content = nil
+}
+
+// Close writes the PDF.
+func (p *pdfWriter) Close() error {
+	p.finishPage()
+	if len(p.pages) == 0 {
+		return fmt.Errorf("pdf has no pages")
+	}
+	kids := make([]string, len(p.pages))
+	for i, pg := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pg)
+	}
+	p.objects[pdfCatalogObj-1] = fmt.Appendf(nil, "<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObj)
+	p.objects[pdfPagesObj-1] = fmt.Appendf(nil, "<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(p.objects))
+	for i, obj := range p.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n", i+1)
+		b.Write(obj)
+		b.WriteString("\nendobj\n")
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(p.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.objects)+1, pdfCatalogObj, xref)
+	_, err := p.w.Write(b.Bytes())
+	return err
+}
+
+// pdfText returns s as the content of a PDF literal string in the
+// Latin-1 subset of WinAnsiEncoding.
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package main
+
+// placeholder serves a built-in "missing image" svg in place of images
+// deleted after the server started, so that a prototype being edited
+// stays navigable.
+
+import (
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// missingImageSVG is the placeholder for missing images.
+const missingImageSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="400" height="300" viewBox="0 0 400 300">
+<rect width="400" height="300" fill="#f0f0f0" stroke="#c0c0c0" stroke-width="4" stroke-dasharray="12 8"/>
+<text x="200" y="155" font-family="Roboto, sans-serif" font-size="20" fill="#808080" text-anchor="middle">missing image</text>
+</svg>
+`
+
+// placeholderImages wraps the images file server handler, serving
+// files from fsys, to respond to requests for missing files with the
+// placeholder svg and a 404 status, logging a warning. Browsers render
+// the placeholder despite the status.
+func placeholderImages(fsys fs.FS, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+		if _, err := fs.Stat(fsys, name); err == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		log.Printf("image warning: %q not found, serving placeholder", name)
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(missingImageSVG))
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerPlaceholderImages(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		placeholders bool
+		path         string
+		status       int
+		contentType  string
+	}{
+		{"present", true, "/images/home.jpg", http.StatusOK, "image/jpeg"},
+		{"missing", true, "/images/deleted.jpg", http.StatusNotFound, "image/svg+xml"},
+		{"missing off", false, "/images/deleted.jpg", http.StatusNotFound, "text/plain; charset=utf-8"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := initServer(t)
+			s.options.Placeholders = tt.placeholders
+
+			handler, err := s.buildHandler()
+			if err != nil {
+				t.Fatal("buildHander error:", err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if got, want := w.Code, tt.status; got != want {
+				t.Errorf("got status %d want %d", got, want)
+			}
+			if got, want := w.Header().Get("Content-Type"), tt.contentType; got != want {
+				t.Errorf("got content type %q want %q", got, want)
+			}
+			if got, want := strings.Contains(w.Body.String(), "missing image"), tt.contentType == "image/svg+xml"; got != want {
+				t.Errorf("placeholder body got %t want %t", got, want)
+			}
+		})
+	}
+}
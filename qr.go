@@ -0,0 +1,40 @@
+package main
+
+// qr prints short texts, such as the index url of a running server, as
+// QR codes to the terminal, so that a prototype can be opened on a
+// phone by scanning the code rather than typing the url.
+
+import (
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// qrString renders code with a quiet zone for a terminal with a dark
+// background, two rows of modules per line using half blocks, light
+// modules being drawn.
+func qrString(code *qr.Code) string {
+	const quiet = 4
+	light := func(r, c int) bool {
+		return !code.Black(c-quiet, r-quiet)
+	}
+	var b strings.Builder
+	width := code.Size + 2*quiet
+	for r := 0; r < width; r += 2 {
+		for c := range width {
+			top, bottom := light(r, c), r+1 >= width || light(r+1, c)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
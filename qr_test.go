@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"rsc.io/qr"
+)
+
+func TestQRString(t *testing.T) {
+	code, err := qr.Encode("http://127.0.0.1:8000/index", qr.L)
+	if err != nil {
+		t.Fatal(err)
+	}
+	width := code.Size + 8
+	lines := strings.Split(strings.TrimSuffix(qrString(code), "\n"), "\n")
+	if got, want := len(lines), (width+1)/2; got != want {
+		t.Fatalf("got %d lines want %d", got, want)
+	}
+	for i, line := range lines {
+		if got := utf8.RuneCountInString(line); got != width {
+			t.Errorf("line %d got width %d want %d", i, got, width)
+		}
+	}
+	// The quiet zone above the code is light.
+	if got, want := lines[0], strings.Repeat("█", width); got != want {
+		t.Errorf("quiet zone got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+// ratelimit provides an optional per client IP token bucket rate
+// limiting middleware to protect publicly shared demos.
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterPruneAge is the period after which limiters for clients that
+// have not been seen are removed.
+const limiterPruneAge time.Duration = 10 * time.Minute
+
+// clientLimiter is a rate limiter for a single client.
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter holds a token bucket rate limiter per client IP.
+type ipRateLimiter struct {
+	mu         sync.Mutex
+	limit      rate.Limit
+	burst      int
+	trustProxy bool
+	clients    map[string]*clientLimiter
+	lastPrune  time.Time
+}
+
+// newIPRateLimiter makes a new ipRateLimiter allowing perSecond
+// requests per second per client IP. If trustProxy is set the client
+// IP is taken from the X-Forwarded-For header when present.
+func newIPRateLimiter(perSecond float64, trustProxy bool) *ipRateLimiter {
+	burst := int(perSecond)
+	if burst < 1 {
+		burst = 1
+	}
+	return &ipRateLimiter{
+		limit:      rate.Limit(perSecond),
+		burst:      burst,
+		trustProxy: trustProxy,
+		clients:    map[string]*clientLimiter{},
+		lastPrune:  time.Now(),
+	}
+}
+
+// allow reports if a request from ip is permitted, occasionally pruning
+// limiters for clients that have not been seen recently.
+func (l *ipRateLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastPrune) > limiterPruneAge {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > limiterPruneAge {
+				delete(l.clients, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c.limiter.Allow()
+}
+
+// middleware returns the rate limiting middleware. The health endpoint
+// is exempt.
+func (l *ipRateLimiter) middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if !l.allow(clientIP(r, l.trustProxy)) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// clientIP determines the client IP of a request, using the first
+// X-Forwarded-For entry only if trustProxy is set.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		trustProxy bool
+		requests   []*http.Request
+		wantCodes  []int
+	}{
+		{
+			name: "second request limited",
+			requests: []*http.Request{
+				httptest.NewRequest("GET", "/home", nil),
+				httptest.NewRequest("GET", "/home", nil),
+			},
+			wantCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name: "health exempt",
+			requests: []*http.Request{
+				httptest.NewRequest("GET", "/health", nil),
+				httptest.NewRequest("GET", "/health", nil),
+				httptest.NewRequest("GET", "/health", nil),
+			},
+			wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:       "forwarded clients distinguished with trust proxy",
+			trustProxy: true,
+			requests: []*http.Request{
+				forwardedRequest("10.0.0.1"),
+				forwardedRequest("10.0.0.2, 192.168.1.1"),
+				forwardedRequest("10.0.0.1"),
+			},
+			wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:       "forwarded header ignored without trust proxy",
+			trustProxy: false,
+			requests: []*http.Request{
+				forwardedRequest("10.0.0.1"),
+				forwardedRequest("10.0.0.2"),
+			},
+			wantCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newIPRateLimiter(0.001, tt.trustProxy)
+			handler := limiter.middleware(okHandler)
+			for i, r := range tt.requests {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				if got, want := w.Code, tt.wantCodes[i]; got != want {
+					t.Errorf("request %d got %d want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// forwardedRequest makes a request with the X-Forwarded-For header set.
+func forwardedRequest(xff string) *http.Request {
+	r := httptest.NewRequest("GET", "/home", nil)
+	r.Header.Set("X-Forwarded-For", xff)
+	return r
+}
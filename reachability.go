@@ -0,0 +1,81 @@
+package main
+
+// reachability reports pages which cannot be reached by following
+// zone, including right and middle click, and auto advance targets from
+// the entry page, as these are dead in the prototype.
+
+import (
+	"fmt"
+	"log"
+)
+
+// reachabilityErrors reports the pages not reachable from the entry
+// page over the zone target graph, as errors in strict mode and
+// otherwise as logged warnings. The entry page is entryURL if set,
+// otherwise that of the config; see entryPage.
+func (c *config) reachabilityErrors(entryURL string) []error {
+	entry := c.entryPage()
+	if entryURL != "" {
+		ii, ok := c.pageForURL(entryURL)
+		if !ok {
+			return []error{ErrInvalidConfig{CodeUnreachablePage, fmt.Sprintf("entry page %q not found", entryURL)}}
+		}
+		entry = ii
+	}
+
+	// Breadth first search over the pages linked by internal targets.
+	reached := map[int]bool{entry: true}
+	queue := []int{entry}
+	for len(queue) > 0 {
+		ii := queue[0]
+		queue = queue[1:]
+		targets := []string{}
+		for _, zo := range c.Pages[ii].Zones {
+			if !zo.External && !zo.Back && zo.Overlay == "" {
+				targets = append(targets, zo.Target)
+			}
+			for _, t := range []string{zo.RightTarget, zo.MiddleTarget} {
+				if t != "" {
+					targets = append(targets, t)
+				}
+			}
+		}
+		if aa := c.Pages[ii].AutoAdvance; aa != nil {
+			targets = append(targets, aa.Target)
+		}
+		for _, target := range targets {
+			next, ok := c.pageForURL(target)
+			if !ok || reached[next] {
+				continue
+			}
+			reached[next] = true
+			queue = append(queue, next)
+		}
+	}
+
+	var errs []error
+	for ii, pg := range c.Pages {
+		if reached[ii] {
+			continue
+		}
+		msg := fmt.Sprintf("page %s (%d) at %s is not reachable from %s", pg.Title, ii, pg.URL, c.Pages[entry].URL)
+		if c.strict {
+			errs = append(errs, ErrInvalidConfig{CodeUnreachablePage, msg})
+		} else {
+			log.Printf("config warning: %s", msg)
+		}
+	}
+	return errs
+}
+
+// entryPage returns the index in Pages of the entry page: the EntryURL
+// page if set, otherwise the page at "/" or else the first page.
+func (c *config) entryPage() int {
+	if ii, ok := c.pageForURL(c.EntryURL); ok && c.EntryURL != "" {
+		return ii
+	}
+	if ii, ok := c.pagesByURL["/"]; ok {
+		return ii
+	}
+	return 0
+}
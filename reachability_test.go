@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfigReachability(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		entryURL string // config EntryURL
+		strict   bool
+		advance  bool   // /detail auto advances to /home
+		pattern  bool   // an /item/{id} page is added
+		alt      string // /detail zone "right" or "middle" click target of /home
+		wantCode ErrorCode
+		wantMsg  string
+	}{
+		{name: "all reachable from the first page"},
+		{name: "unreachable warning", entry: "/detail"},
+		{name: "unreachable strict", entry: "/detail", strict: true, wantCode: CodeUnreachablePage, wantMsg: "/home is not reachable from /detail"},
+		{name: "reachable by auto advance", entry: "/detail", strict: true, advance: true},
+		{name: "reachable by right click", entry: "/detail", strict: true, alt: "right"},
+		{name: "reachable by middle click", entry: "/detail", strict: true, alt: "middle"},
+		{name: "missing entry", entry: "/nope", wantCode: CodeUnreachablePage, wantMsg: "not found"},
+		{name: "config entry strict", entryURL: "/detail", strict: true, wantCode: CodeUnreachablePage, wantMsg: "/home is not reachable from /detail"},
+		{name: "invalid config entry", entryURL: "/nope", wantCode: CodeInvalidEntryURL, wantMsg: "not a page URL"},
+		{name: "pattern config entry", entryURL: "/item/{id}", pattern: true, wantCode: CodeInvalidEntryURL, wantMsg: "has path variables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := initServerConfig(t)
+			c.Pages[1].Zones[0].Target = "/detail" // only /home leads to /detail
+			c.checkReachability = true
+			c.entryURL = tt.entry
+			c.EntryURL = tt.entryURL
+			c.strict = tt.strict
+			if tt.advance {
+				c.Pages[1].AutoAdvance = &autoAdvance{Target: "/home", AfterMs: 2000}
+			}
+			switch tt.alt {
+			case "right":
+				c.Pages[1].Zones[0].RightTarget = "/home"
+			case "middle":
+				c.Pages[1].Zones[0].MiddleTarget = "/home"
+			}
+			if tt.pattern {
+				c.Pages = append(c.Pages, page{URL: "/item/{id}", Title: "Item", ImagePath: "images/detail.jpg", Zones: c.Pages[1].Zones})
+			}
+			err := c.validateConfig()
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var eic ErrInvalidConfig
+			if !errors.As(err, &eic) || eic.Code != tt.wantCode || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("expected %s error containing %q, got %v", tt.wantCode, tt.wantMsg, err)
+			}
+		})
+	}
+}
+
+// TestSplashEntryURL checks that the splash template is given the
+// config entry url, or by default the first page.
+func TestSplashEntryURL(t *testing.T) {
+	for _, entryURL := range []string{"", "/detail"} {
+		c := initServerConfig(t)
+		c.EntryURL = entryURL
+		if err := c.validateConfig(); err != nil {
+			t.Fatal(err)
+		}
+		s, err := newServer("127.0.0.1", "8000", c, ServerOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := entryURL
+		if want == "" {
+			want = "/home"
+		}
+		if got := s.splashData().EntryURL; got != want {
+			t.Errorf("entry url %q got %q want %q", entryURL, got, want)
+		}
+	}
+}
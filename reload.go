@@ -0,0 +1,92 @@
+package main
+
+// reload swaps the handler of a running server for one built from a
+// re-read configuration, such as on SIGHUP in serve mode, without
+// disrupting in-flight requests.
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// atomicHandler is an http.Handler whose underlying handler can be
+// swapped atomically while serving. Requests in flight complete using
+// the handler they started with.
+type atomicHandler struct {
+	h atomic.Pointer[http.Handler]
+}
+
+// ServeHTTP serves the request with the current handler.
+func (a *atomicHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*a.h.Load()).ServeHTTP(w, r)
+}
+
+// store sets the current handler.
+func (a *atomicHandler) store(h http.Handler) {
+	a.h.Store(&h)
+}
+
+// loaded reports if a handler has been stored.
+func (a *atomicHandler) loaded() bool {
+	return a.h.Load() != nil
+}
+
+// reload builds a handler for the validated configuration cfg, using
+// the address, port and options of s, and swaps it in for the current
+// handler. The current handler is kept if the new one cannot be built.
+// The idle tracker and rate limiter of s, if any, are shared with the
+// new handler.
+func (s *server) reload(cfg *config) error {
+	newSrv, err := newServer(s.serverAddress, s.serverPort, cfg, s.options)
+	if err != nil {
+		return err
+	}
+	newSrv.idle = s.idle
+	newSrv.limiter = s.limiter
+	h, err := newSrv.buildHandler()
+	if err != nil {
+		return err
+	}
+	s.handler.store(h)
+	return nil
+}
+
+// reloadConfig re-reads and validates configFile and reloads s with
+// it, logging the result. On error the current handler is kept.
+func reloadConfig(s *server, configFile string) {
+	cfg, err := loadServeConfig(configFile, s.options)
+	if err == nil {
+		err = s.reload(cfg)
+	}
+	if err != nil {
+		log.Printf("config reload error, keeping the current config: %v", err)
+		return
+	}
+	log.Printf("config %q reloaded", configFile)
+}
+
+// reloadOnHangup reloads s from configFile on each SIGHUP until the
+// returned stop function is called.
+func reloadOnHangup(s *server, configFile string) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				reloadConfig(s, configFile)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(done)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAtomicHandlerInFlight checks that a request in flight during a
+// swap completes with the handler it started with.
+func TestAtomicHandlerInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var ah atomicHandler
+	ah.store(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "old")
+	}))
+	ts := httptest.NewServer(&ah)
+	defer ts.Close()
+
+	get := func() string {
+		resp, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Errorf("get error: %v", err)
+			return ""
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	inFlight := make(chan string)
+	go func() {
+		inFlight <- get()
+	}()
+	<-started
+	ah.store(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "new")
+	}))
+	if got, want := get(), "new"; got != want {
+		t.Errorf("new request got %q want %q", got, want)
+	}
+	close(release)
+	if got, want := <-inFlight, "old"; got != want {
+		t.Errorf("in flight request got %q want %q", got, want)
+	}
+}
+
+// TestReloadConfig checks that a server is reloaded with a valid config
+// and keeps its current config if the new one is invalid.
+func TestReloadConfig(t *testing.T) {
+	configFile := writeConfig(t, []byte(makeOKConfig(t, false)))
+	t.Cleanup(func() { _ = os.Remove(configFile) })
+
+	cfg, err := newConfigDir([]byte(makeOKConfig(t, false)), ".", configOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer("127.0.0.1", "8000", cfg, ServerOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.handler.store(handler)
+
+	status := func(url string) int {
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Code
+	}
+	if got, want := status("/about"), http.StatusNotFound; got != want {
+		t.Fatalf("about before reload got %d want %d", got, want)
+	}
+
+	// rename the detail page to about
+	renamed := strings.NewReplacer(`"/detail"`, `"/about"`, `"Detail"`, `"About"`, "detail.jpg", "about.jpg").Replace(makeOKConfig(t, false))
+	if err := os.WriteFile(configFile, []byte(renamed), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(s, configFile)
+	if got, want := status("/about"), http.StatusOK; got != want {
+		t.Errorf("about after reload got %d want %d", got, want)
+	}
+
+	if err := os.WriteFile(configFile, []byte(makeNotOKConfig(t, false)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(s, configFile)
+	if got, want := status("/about"), http.StatusOK; got != want {
+		t.Errorf("about after invalid reload got %d want %d", got, want)
+	}
+}
+
+// TestReloadConfigNote checks that a changed page note is rendered
+// afresh from markdown on reload.
+func TestReloadConfigNote(t *testing.T) {
+	withNote := func(note string) string {
+		return strings.Replace(makeOKConfig(t, false), `    Title: "Home"`, `    Title: "Home"`+"\n    Note: \""+note+"\"", 1)
+	}
+	configFile := writeConfig(t, []byte(withNote("a *first* note")))
+	t.Cleanup(func() { _ = os.Remove(configFile) })
+
+	cfg, err := loadServeConfig(configFile, ServerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer("127.0.0.1", "8000", cfg, ServerOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.handler.store(handler)
+
+	body := func() string {
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+		return w.Body.String()
+	}
+	if got, want := body(), "<em>first</em>"; !strings.Contains(got, want) {
+		t.Fatalf("note before reload does not contain %q", want)
+	}
+
+	if err := os.WriteFile(configFile, []byte(withNote("a **second** note")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(s, configFile)
+	got := body()
+	if want := "<strong>second</strong>"; !strings.Contains(got, want) {
+		t.Errorf("note after reload does not contain %q", want)
+	}
+	if strings.Contains(got, "first") {
+		t.Error("note after reload still contains the first note")
+	}
+}
+
+// TestServeInDevelopmentReload checks that a config change in develop
+// mode swaps the handler of the running server, with the page note
+// rendered afresh, rather than starting another server.
+func TestServeInDevelopmentReload(t *testing.T) {
+	withNote := func(note string) string {
+		return strings.Replace(makeOKConfig(t, false), `    Title: "Home"`, `    Title: "Home"`+"\n    Note: \""+note+"\"", 1)
+	}
+	// The watcher watches files with the config file's extension.
+	tf, err := os.CreateTemp(".", testFilePattern+".yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = tf.Close()
+	configFile := tf.Name()
+	t.Cleanup(func() { _ = os.Remove(configFile) })
+	if err := os.WriteFile(configFile, []byte(withNote("a *first* note")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	servers := make(chan *server, 2)
+	app := App{
+		serveFunc: func(s *server) error {
+			err := Serve(s)
+			servers <- s
+			return err
+		},
+		stopper: make(chan struct{}),
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- app.ServeInDevelopment("127.0.0.1", "8000", []string{"html"}, configFile, ServerOptions{Quiet: true, webServer: &stubWebServer{}}, DevelopOptions{})
+	}()
+
+	var s *server
+	select {
+	case s = <-servers:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server not started")
+	}
+	body := func() string {
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+		return w.Body.String()
+	}
+	if got, want := body(), "<em>first</em>"; !strings.Contains(got, want) {
+		t.Fatalf("note before reload does not contain %q", want)
+	}
+
+	// The config is rewritten until the reload is seen, as the file
+	// watcher may not yet be watching when the server has started.
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(body(), "<strong>second</strong>") {
+		if time.Now().After(deadline) {
+			t.Fatal("note not reloaded")
+		}
+		if err := os.WriteFile(configFile, []byte(withNote("a **second** note")), 0600); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	select {
+	case <-servers:
+		t.Error("a second server was started on reload")
+	default:
+	}
+
+	app.stopper <- struct{}{}
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -8,53 +8,182 @@ package main
 // dynamically provisione based on the yaml config file.
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"html/template"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/netutil"
 )
 
+// WebServer is a server which listens and serves until closed. It may
+// be set in ServerOptions, for testing, to replace the listening
+// http.Server.
 type WebServer interface {
 	ListenAndServe() error
 }
 
+// Default asset directory names, which may be changed in the config.
 const (
 	imageDir    = "images"
 	staticDir   = "static"
 	templateDir = "templates"
 )
 
+// ServerOptions are runtime options for the server set from the
+// command line, as distinct from the yaml configuration.
+type ServerOptions struct {
+	RateLimit  float64 // requests per second per client IP; 0 is off
+	TrustProxy bool    // take the client IP from X-Forwarded-For
+	Quiet      bool    // suppress interactive messages and access logs, but not session logs
+	H2C        bool    // serve unencrypted HTTP/2 (h2c) as well as HTTP/1
+
+	MaxBodyBytes int64 // maximum non-GET request body size; 0 is unlimited
+	NoRecover    bool  // omit the panic recovery middleware
+
+	RequestTimeout time.Duration // page and index handler deadline; 0 is off
+	SlowThreshold  time.Duration // log requests taking longer than this; 0 is off
+	Pprof          bool          // mount the pprof handlers at /debug/pprof/
+	MaxConns       int           // maximum concurrent connections; 0 is unlimited
+	Precompile     bool          // render each template at startup to fail fast
+	Strict         bool          // treat config warnings, such as small zones, as errors
+	Overlay        string        // config file merged over the served config file, if set
+	AssetsDir      string        // replaces the config's assetsDir, relative to the config file, if set
+	ETag           bool          // serve pages with an ETag, honouring If-None-Match
+	ShowZones      bool          // outline each zone with its target for debugging
+	HSTS           bool          // require https with Strict-Transport-Security; needs TLS
+	PortScan       bool          // try successive ports if the port is in use (see bindScan)
+	QR             bool          // print a QR code of the index url when interactive
+	CachePages     bool          // render pages once when the handler is built
+	SessionParam   string        // query parameter identifying sessions in the log; "" is off
+	ServerHeader   *string       // Server response header, omitted if empty; nil is no change
+	Placeholders   bool          // serve a placeholder svg for missing images
+	IdleShutdown   time.Duration // shut down after this long without requests; 0 is off
+
+	// StrictTemplates fails on templates referencing missing keys,
+	// such as an unset .Meta value, rather than rendering "<no value>".
+	StrictTemplates bool
+
+	// TLSAuto serves https on port 443 with Let's Encrypt certificates
+	// for Domains, cached in CertCacheDir, redirecting http on port 80.
+	TLSAuto      bool
+	Domains      []string
+	CertCacheDir string
+
+	devOverlay *devOverlay // development mode error overlay
+	logWriter  io.Writer   // access and session log; os.Stdout if nil
+	webServer  WebServer   // replaces listening on a port, for testing
+}
+
 // server sets the configuration for a simple http server.
 type server struct {
-	imagePath     string // "/images/"
-	staticPath    string
-	templatesPath string
-	serverAddress string
-	serverPort    string
-	assetsFS      fs.FS
-	pageTpl       *template.Template
-	indexTpl      *template.Template
-	pages         []page
-	indexPages    []string
-	webServer     *http.Server
+	imageDir       string // "images"
+	staticDir      string
+	imagePath      string // "/images/"
+	staticPath     string
+	templatesPath  string
+	serverAddress  string
+	serverPort     string
+	assetsFS       fs.FS
+	favicon        string
+	faviconICO     []byte // rasterized favicon, if available
+	headers        map[string]string
+	allowedOrigins []string          // CORS origins
+	redirects      map[string]string // legacy paths to page URLs
+	assetsURL      string            // external base url of images and static files, if set
+	entryURL       string            // url of the entry page
+	tour           []string          // ordered page urls of the tour, if set
+	staticExts     []string          // extensions served from the static directory, if set
+	lang           string            // content language of the pages
+	pageTpl        *template.Template
+	indexTpl       *template.Template
+	splashTpl      *template.Template // landing page at "/", if set
+	errorTpl       *template.Template // internal error page, if set
+	pageCache      map[string][]byte  // rendered page html by url, with CachePages
+	pages          []page
+	pagesByURL     map[string]int // index in pages by page url
+	orderedPages   []page         // linkable pages sorted by Order for the index and navigation
+	indexPages     []string
+	options        ServerOptions
+	webServer      *http.Server
+	certManager    *autocert.Manager // automatic TLS, if set
+	idle           *idleTracker      // idle shutdown, if set
+	limiter        *ipRateLimiter    // per client IP rate limiting, if set
+	handler        atomicHandler     // the served handler, swapped on reload
+	listener       net.Listener      // bound before Serve by bindScan, if set
+}
+
+// templateData is the data provided to the page and index templates,
+// allowing templates to build navigation from the full list of pages.
+// Page is nil for the index template.
+type templateData struct {
+	Page       *page
+	AllPages   []page
+	IndexPaths []string
+
+	// Params are the path variables matched by a parameterized page
+	// URL such as "/item/{id}".
+	Params map[string]string
+
+	// Error is the message of the internal error reported by the error
+	// template.
+	Error string
+
+	// EntryURL is the url of the page at which the prototype starts,
+	// for the "Start" link of the splash template.
+	EntryURL string
+
+	// AssetsURL is the base url of images and static files if they
+	// are served externally, such as from a CDN, otherwise "".
+	AssetsURL string
+
+	// StaticPath and ImagesPath are the url paths, such as "/static",
+	// at which the static and images directories are served.
+	StaticPath string
+	ImagesPath string
+
+	// Lang is the content language of the page, such as "en", for the
+	// html lang attribute.
+	Lang string
+
+	// TourPrev and TourNext are the urls of the previous and next
+	// pages of the config's tour, if the page is on the tour.
+	TourPrev string
+	TourNext string
+
+	// BackURL is the href of "back" zones, used when JavaScript is not
+	// available to go back in the browser history.
+	BackURL string
+
+	// ShowZones is set to outline the zones of a page for debugging,
+	// with the --show-zones flag or a "zones=1" query.
+	ShowZones bool
 }
 
 // newServer makes a newServer
 func newServer(
 	address, port string,
 	cfg *config,
+	options ServerOptions,
 ) (*server, error) {
 
 	if a := net.ParseIP(address); a == nil {
@@ -63,10 +192,29 @@ func newServer(
 	if _, err := strconv.Atoi(port); err != nil {
 		return nil, fmt.Errorf("invalid port: %s", port)
 	}
+	if options.RateLimit < 0 {
+		return nil, fmt.Errorf("invalid rate limit: %v", options.RateLimit)
+	}
+	if options.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("invalid max body bytes: %d", options.MaxBodyBytes)
+	}
+	if options.RequestTimeout < 0 {
+		return nil, fmt.Errorf("invalid request timeout: %v", options.RequestTimeout)
+	}
+	if options.SlowThreshold < 0 {
+		return nil, fmt.Errorf("invalid slow threshold: %v", options.SlowThreshold)
+	}
+	if options.MaxConns < 0 {
+		return nil, fmt.Errorf("invalid max conns: %d", options.MaxConns)
+	}
+	if options.IdleShutdown < 0 {
+		return nil, fmt.Errorf("invalid idle shutdown: %v", options.IdleShutdown)
+	}
 
 	s := server{
 		serverAddress: address,
 		serverPort:    port,
+		options:       options,
 	}
 
 	// The default server is an http.Server. This can be overridden for
@@ -81,14 +229,58 @@ func newServer(
 		ReadHeaderTimeout: 2 * time.Second,
 	}
 
+	// Optionally speak plaintext HTTP/2 for use behind a proxy. This
+	// uses net/http's Protocols support, which replaces the deprecated
+	// golang.org/x/net/http2/h2c handler.
+	if options.H2C {
+		s.webServer.Protocols = new(http.Protocols)
+		s.webServer.Protocols.SetHTTP1(true)
+		s.webServer.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	// Optionally serve https with automatic certificates.
+	if options.TLSAuto {
+		if len(options.Domains) == 0 {
+			return nil, errors.New("tls-auto requires at least one domain")
+		}
+		if options.H2C {
+			return nil, errors.New("tls-auto and h2c cannot be used together")
+		}
+		s.serverPort = tlsAutoPort
+		s.webServer.Addr = net.JoinHostPort(s.serverAddress, s.serverPort)
+		s.certManager = newCertManager(options.Domains, options.CertCacheDir)
+		s.webServer.TLSConfig = s.certManager.TLSConfig()
+		if cfg.TLSMin != 0 {
+			s.webServer.TLSConfig.MinVersion = cfg.TLSMin
+		}
+		s.webServer.TLSConfig.CipherSuites = cfg.TLSCipherSuites
+	}
+	if options.HSTS && !options.TLSAuto {
+		log.Print("hsts warning: hsts has no effect without tls, ignoring")
+	}
+	if (cfg.TLSMin != 0 || len(cfg.TLSCipherSuites) > 0) && !options.TLSAuto {
+		log.Print("tls warning: tlsMinVersion and tlsCiphers have no effect without tls, ignoring")
+	}
+
 	pather := func(dir string) string {
-		return "/" + filepath.Base(dir) + "/"
+		return "/" + path.Clean(dir) + "/"
 	}
-	s.imagePath = pather(imageDir)
-	s.staticPath = pather(staticDir)
-	s.templatesPath = pather(templateDir)
+	cfg.Dirs.setDefaults()
+	s.imageDir = cfg.Dirs.Images
+	s.staticDir = cfg.Dirs.Static
+	s.imagePath = pather(cfg.Dirs.Images)
+	s.staticPath = pather(cfg.Dirs.Static)
+	s.templatesPath = pather(cfg.Dirs.Templates)
 
 	s.assetsFS = cfg.AssetsFS
+	s.favicon = cfg.Favicon
+	s.initFaviconICO()
+	s.headers = cfg.Headers
+	s.allowedOrigins = cfg.AllowedOrigins
+	s.redirects = cfg.Redirects
+	s.assetsURL = cfg.AssetsURL
+	s.staticExts = cfg.StaticAllowedExtensions
+	s.lang = cfg.Lang
 
 	var err error
 
@@ -96,25 +288,90 @@ func newServer(
 		return nil, errors.New("at least two pages must be provided")
 	}
 	s.pages = cfg.Pages
+	s.pagesByURL = cfg.pagesByURL
+	s.entryURL = cfg.EntryURL
+	if s.entryURL == "" {
+		s.entryURL = cfg.Pages[cfg.entryPage()].URL
+	}
+	s.tour = cfg.Tour
+	s.orderedPages = cfg.OrderedPages
+	if s.orderedPages == nil {
+		s.orderedPages = orderPages(cfg.Pages)
+	}
+	s.orderedPages = linkablePages(s.orderedPages)
 
 	// Attach template.
 	s.pageTpl = cfg.PageTpl
 	s.indexTpl = cfg.IndexTpl
+	s.splashTpl = cfg.SplashTpl
+	s.errorTpl = cfg.ErrorTpl
 
 	// Determine if page indexes are needed.
-	s.indexPages = []string{}
-	for _, idx := range []string{"/index", "/"} {
-		if cfg.hasURL(idx) {
-			continue
+	s.indexPages = computeIndexPages(cfg)
+
+	if options.IdleShutdown > 0 {
+		s.idle = newIdleTracker(options.IdleShutdown)
+	}
+	if options.RateLimit > 0 {
+		s.limiter = newIPRateLimiter(options.RateLimit, options.TrustProxy)
+	}
+
+	// Optionally render each template once to fail fast.
+	if options.Precompile {
+		if err := s.precompile(); err != nil {
+			return nil, err
 		}
-		s.indexPages = append(s.indexPages, idx)
 	}
 
 	return &s, err
 }
 
-// HealthCheck shows if the service is up
+// acceptQuality returns the quality value given to mediaType by the
+// Accept header value accept, taking the most specific matching media
+// range. Zero is returned if there is no match.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+		var spec int
+		switch mediaRange {
+		case mediaType:
+			spec = 2
+		case typ + "/*":
+			spec = 1
+		case "*/*":
+			spec = 0
+		default:
+			continue
+		}
+		if spec < specificity {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+		quality, specificity = q, spec
+	}
+	return quality
+}
+
+// HealthCheck shows if the service is up. The status is returned as
+// JSON unless the request's Accept header prefers text/plain.
 func (s *server) Health(w http.ResponseWriter, r *http.Request) {
+	accept := r.Header.Get("Accept")
+	if acceptQuality(accept, "text/plain") > acceptQuality(accept, "application/json") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = io.WriteString(w, "up\n")
+		return
+	}
 	enc := json.NewEncoder(w)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	resp := map[string]string{"status": "up"}
@@ -123,29 +380,94 @@ func (s *server) Health(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Favicon serves up the favicon
+// Favicon serves up the favicon, either from the inline svg content or
+// the path provided by the configuration.
 func (s *server) Favicon(w http.ResponseWriter, r *http.Request) {
-	http.ServeFileFS(w, r, s.assetsFS, "/static/favicon.svg")
+	if isInlineSVG(s.favicon) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		_, _ = w.Write([]byte(s.favicon))
+		return
+	}
+	http.ServeFileFS(w, r, s.assetsFS, s.faviconPath())
 }
 
-// Page provides an httphandler for each page.
+// Page provides an httphandler for each page. With the "inline=1"
+// query the page is rendered as standalone html with its assets
+// inlined from the assets filesystem, even if an assetsURL is set.
 func (s *server) Page(p *page, tpl *template.Template) (http.HandlerFunc, error) {
 	if _, err := fs.Stat(s.assetsFS, p.ImagePath); err != nil {
 		return nil, fmt.Errorf("%s: image %s not found", p.URL, p.ImagePath)
 	}
-	if len(p.Zones) < 1 {
+	if len(p.Zones) < 1 && !p.Terminal {
 		return nil, fmt.Errorf("%s: need a least one zone", p.URL)
 	}
 
+	data := s.pageData(p)
+	cached := s.pageCache[p.URL]
 	return func(w http.ResponseWriter, r *http.Request) {
+		data := data
+		data.Params = mux.Vars(r)
+		data.ShowZones = s.options.ShowZones || r.URL.Query().Get("zones") == "1"
 		w.Header().Set("Content-Type", "text/html")
-		err := tpl.Execute(w, p)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		setContentLanguage(w, data.Lang)
+		inline := r.URL.Query().Get("inline") == "1"
+		if inline {
+			data.AssetsURL = ""
+		}
+
+		// Serve the cached html unless a zones or inline query changes
+		// it.
+		b := cached
+		if b == nil || data.ShowZones != s.options.ShowZones || data.AssetsURL != s.assetsURL {
+			if !inline && !s.options.ETag && s.errorTpl == nil {
+				if err := tpl.Execute(w, data); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			var buf bytes.Buffer
+			if err := tpl.Execute(&buf, data); err != nil {
+				s.internalError(w, err)
+				return
+			}
+			b = buf.Bytes()
+		}
+		if inline {
+			b = inlineHTML(b, s.assetsFS)
 		}
+		s.write(w, r, b)
 	}, nil
 }
 
+// linkablePages returns pages without those with path variables, such
+// as "/item/{id}", which have no single url to link to from the index
+// and navigation.
+func linkablePages(pages []page) []page {
+	return slices.DeleteFunc(slices.Clone(pages), func(p page) bool {
+		return isURLPattern(p.URL)
+	})
+}
+
+// renderPages renders the html of each page without path variables,
+// keyed by url, for the CachePages option.
+func (s *server) renderPages() (map[string][]byte, error) {
+	cache := map[string][]byte{}
+	for i := range s.pages {
+		p := &s.pages[i]
+		if isURLPattern(p.URL) {
+			continue
+		}
+		data := s.pageData(p)
+		data.ShowZones = s.options.ShowZones
+		var buf bytes.Buffer
+		if err := s.pageTpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("%s: page render error: %w", p.URL, err)
+		}
+		cache[p.URL] = buf.Bytes()
+	}
+	return cache, nil
+}
+
 // FourOhFour provides a 404 handler.
 func (s *server) FourOhFour(message string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -154,15 +476,198 @@ func (s *server) FourOhFour(message string) http.HandlerFunc {
 	}
 }
 
+// indexURLs are the urls served by an automatic index, in order, unless
+// claimed by a page or, for "/", the splash template.
+var indexURLs = []string{"/index", "/"}
+
+// computeIndexPages returns the indexURLs not claimed by a page url or
+// the splash template in cfg, in indexURLs order and without
+// duplicates. The result is empty, not nil, if every index url is
+// claimed.
+func computeIndexPages(cfg *config) []string {
+	claimed := map[string]bool{}
+	for _, p := range cfg.Pages {
+		claimed[p.URL] = true
+	}
+	if cfg.SplashTemplate != "" {
+		claimed["/"] = true
+	}
+	indexPages := []string{}
+	for _, idx := range indexURLs {
+		if claimed[idx] || slices.Contains(indexPages, idx) {
+			continue
+		}
+		indexPages = append(indexPages, idx)
+	}
+	return indexPages
+}
+
+// pageData returns the template data for page p, including its
+// previous and next tour urls if p is on the tour.
+func (s *server) pageData(p *page) templateData {
+	data := templateData{
+		Page:       p,
+		AllPages:   s.orderedPages,
+		IndexPaths: s.indexPages,
+		AssetsURL:  s.assetsURL,
+		StaticPath: strings.TrimSuffix(s.staticPath, "/"),
+		ImagesPath: strings.TrimSuffix(s.imagePath, "/"),
+		Lang:       s.lang,
+		BackURL:    s.backURL(),
+	}
+	if p.Lang != "" {
+		data.Lang = p.Lang
+	}
+	if i := slices.Index(s.tour, p.URL); i >= 0 {
+		if i > 0 {
+			data.TourPrev = s.tour[i-1]
+		}
+		if i < len(s.tour)-1 {
+			data.TourNext = s.tour[i+1]
+		}
+	}
+	return data
+}
+
+// backURL returns the fallback href of "back" zones: the first
+// automatic index url or, if every index url is claimed, the entry
+// page.
+func (s *server) backURL() string {
+	if len(s.indexPages) > 0 {
+		return s.indexPages[0]
+	}
+	return s.entryURL
+}
+
+// splashData returns the template data for the splash template, with
+// the url of the entry page.
+func (s *server) splashData() templateData {
+	data := s.indexData(s.orderedPages)
+	data.EntryURL = s.entryURL
+	return data
+}
+
+// indexData returns the template data for an index of pages.
+func (s *server) indexData(pages []page) templateData {
+	return templateData{
+		AllPages:   pages,
+		IndexPaths: s.indexPages,
+		AssetsURL:  s.assetsURL,
+		StaticPath: strings.TrimSuffix(s.staticPath, "/"),
+		ImagesPath: strings.TrimSuffix(s.imagePath, "/"),
+		Lang:       s.lang,
+	}
+}
+
+// precompile renders each page, and the index, once to surface template
+// execution errors at startup rather than on request.
+func (s *server) precompile() error {
+	for i := range s.pages {
+		p := &s.pages[i]
+		if err := s.pageTpl.Execute(io.Discard, s.pageData(p)); err != nil {
+			return fmt.Errorf("page %s template error: %w", p.URL, err)
+		}
+	}
+	if err := s.indexTpl.Execute(io.Discard, s.indexData(s.orderedPages)); err != nil {
+		return fmt.Errorf("index template error: %w", err)
+	}
+	if s.splashTpl != nil {
+		if err := s.splashTpl.Execute(io.Discard, s.splashData()); err != nil {
+			return fmt.Errorf("splash template error: %w", err)
+		}
+	}
+	if s.errorTpl != nil {
+		data := s.indexData(s.orderedPages)
+		data.Error = "precompile"
+		if err := s.errorTpl.Execute(io.Discard, data); err != nil {
+			return fmt.Errorf("error template error: %w", err)
+		}
+	}
+	return nil
+}
+
 // Index provides an index of all pages.
 func (s *server) Index(pages []page, tpl *template.Template) http.HandlerFunc {
+	return s.serveTemplate(tpl, s.indexData(pages))
+}
+
+// Splash provides the landing page handler, rendering tpl with a link
+// to the entry page.
+func (s *server) Splash(tpl *template.Template) http.HandlerFunc {
+	return s.serveTemplate(tpl, s.splashData())
+}
+
+// serveTemplate returns a handler rendering tpl with data, with an ETag
+// if the ETag option is set.
+func (s *server) serveTemplate(tpl *template.Template, data templateData) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
-		err := tpl.Execute(w, pages)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		setContentLanguage(w, data.Lang)
+		if !s.options.ETag && s.errorTpl == nil {
+			if err := tpl.Execute(w, data); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			s.internalError(w, err)
+			return
 		}
+		s.write(w, r, buf.Bytes())
+	}
+}
+
+// noWriteDeadline wraps handler to clear the server's write deadline
+// for long running responses, such as the pprof cpu profile.
+func noWriteDeadline(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		handler(w, r)
+	}
+}
+
+// setContentLanguage sets the Content-Language header to lang, if set.
+func setContentLanguage(w http.ResponseWriter, lang string) {
+	if lang != "" {
+		w.Header().Set("Content-Language", lang)
+	}
+}
+
+// internalError responds with a 500 status, rendering the error
+// template with the message of err if set, and otherwise, or if the
+// error template itself fails, with a plain text message.
+func (s *server) internalError(w http.ResponseWriter, err error) {
+	if s.errorTpl == nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := s.indexData(s.orderedPages)
+	data.Error = err.Error()
+	var buf bytes.Buffer
+	if terr := s.errorTpl.Execute(&buf, data); terr != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	setContentLanguage(w, data.Lang)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// requestTimeoutMessage is the body of the response to a page or index
+// request that exceeds the request timeout.
+const requestTimeoutMessage = "The request timed out."
+
+// withTimeout wraps handler in an http.TimeoutHandler if a request
+// timeout is set, responding with a 503 if the handler does not
+// complete in time. It is used for pages and the index: image and
+// static file serving is exempt.
+func (s *server) withTimeout(handler http.Handler) http.Handler {
+	if s.options.RequestTimeout <= 0 {
+		return handler
 	}
+	return http.TimeoutHandler(handler, s.options.RequestTimeout, requestTimeoutMessage)
 }
 
 // buildHandler builds the http handler.
@@ -176,27 +681,43 @@ func (s *server) buildHandler() (http.Handler, error) {
 	// is a catch-all pattern.
 	r := mux.NewRouter()
 
-	// Attach the images and static directories.
-	imgFS, err := fs.Sub(s.assetsFS, imageDir)
-	if err != nil {
-		return nil, fmt.Errorf("image fs mount failure: %w", err)
-	}
-	r.PathPrefix(s.imagePath).Handler(http.StripPrefix(s.imagePath, http.FileServerFS(imgFS)))
-
-	staticFS, err := fs.Sub(s.assetsFS, staticDir)
-	if err != nil {
-		return nil, fmt.Errorf("static fs mount failure: %w", err)
-	}
-	r.PathPrefix(s.staticPath).Handler(http.StripPrefix(s.staticPath, http.FileServerFS(staticFS)))
-
 	// Don't allow /templates to be read
 	r.HandleFunc(s.templatesPath, s.FourOhFour(
 		"The templates directory is purposely not mounted.",
 	))
 
 	r.HandleFunc("/health", s.Health)
+	r.HandleFunc("/__sitemap", s.Sitemap)
+	r.HandleFunc("/__zones", s.Zones)
+	r.HandleFunc("/__note/{url:.*}", s.Note)
 	r.HandleFunc("/favicon", s.Favicon)
-	r.HandleFunc("/favicon.ico", s.Favicon)
+	r.HandleFunc("/favicon.ico", s.FaviconICO)
+
+	// Optionally mount the profiling endpoints. Index also serves the
+	// named profiles such as /debug/pprof/heap. The cpu profile and
+	// trace run for seconds, so are exempt from the write timeout.
+	if s.options.Pprof {
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", noWriteDeadline(pprof.Profile))
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", noWriteDeadline(pprof.Trace))
+		r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	}
+
+	// Attach the redirects of legacy paths to pages.
+	for from, to := range s.redirects {
+		r.Handle(from, http.RedirectHandler(to, http.StatusMovedPermanently))
+	}
+
+	// Optionally render the pages once, the cache being rebuilt with
+	// the handler on config reloads.
+	if s.options.CachePages {
+		cache, err := s.renderPages()
+		if err != nil {
+			return nil, fmt.Errorf("page cache error: %w", err)
+		}
+		s.pageCache = cache
+	}
 
 	// Attach the pages defined in the configuration file.
 	for _, p := range s.pages {
@@ -205,43 +726,274 @@ func (s *server) buildHandler() (http.Handler, error) {
 			return nil, fmt.Errorf("page build error: %w", err)
 		}
 		// add route
-		r.HandleFunc(p.URL, pe)
+		r.Handle(p.URL, s.withTimeout(pe))
 	}
 
 	// Attach index pages if required.
 	for _, idx := range s.indexPages {
-		r.HandleFunc(idx, s.Index(s.pages, s.indexTpl))
+		r.Handle(idx, s.withTimeout(s.Index(s.orderedPages, s.indexTpl)))
+	}
+
+	// Attach the splash page, if set, in place of the "/" index.
+	if s.splashTpl != nil {
+		r.Handle("/", s.withTimeout(s.Splash(s.splashTpl)))
+	}
+
+	// Attach the images and static directories, unless they are served
+	// from an external assets url. These prefix routes are attached
+	// after the pages and indexes, as mux matches routes in the order
+	// they are attached, so that a prefix cannot capture "/" or a page.
+	if s.assetsURL == "" {
+		imgFS, err := fs.Sub(s.assetsFS, s.imageDir)
+		if err != nil {
+			return nil, fmt.Errorf("image fs mount failure: %w", err)
+		}
+		var imgHandler http.Handler = http.FileServerFS(imgFS)
+		if s.options.Placeholders {
+			imgHandler = placeholderImages(imgFS, imgHandler)
+		}
+		r.PathPrefix(s.imagePath).Handler(http.StripPrefix(s.imagePath, imgHandler))
+
+		staticFS, err := fs.Sub(s.assetsFS, s.staticDir)
+		if err != nil {
+			return nil, fmt.Errorf("static fs mount failure: %w", err)
+		}
+		var staticHandler http.Handler = http.FileServerFS(staticFS)
+		if len(s.staticExts) > 0 {
+			staticHandler = allowedExtensions(s.staticExts, staticHandler)
+		}
+		r.PathPrefix(s.staticPath).Handler(http.StripPrefix(s.staticPath, staticHandler))
 	}
 
 	// logging converts gorilla's handlers.CombinedLoggingHandler to a
-	// func(http.Handler) http.Handler to satisfy type MiddlewareFunc
+	// func(http.Handler) http.Handler to satisfy type MiddlewareFunc,
+	// discarding the log in quiet mode
+	var logWriter io.Writer = os.Stdout
+	if s.options.logWriter != nil {
+		logWriter = s.options.logWriter
+	}
+	if s.options.Quiet {
+		logWriter = io.Discard
+	}
 	logging := func(handler http.Handler) http.Handler {
-		return handlers.CombinedLoggingHandler(os.Stdout, handler)
+		return handlers.CombinedLoggingHandler(logWriter, handler)
 	}
 
 	// recovery converts gorilla's handlers.RecoveryHandler to a
-	// func(http.Handler) http.Handler to satisfy type MiddlewareFunc
+	// func(http.Handler) http.Handler to satisfy type MiddlewareFunc.
+	// Without it panics propagate to net/http, which logs the stack.
 	recovery := func(handler http.Handler) http.Handler {
 		return handlers.RecoveryHandler()(handler)
 	}
 
+	// customHeaders sets the headers provided by the configuration on
+	// all responses.
+	customHeaders := func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range s.headers {
+				w.Header().Set(k, v)
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+
 	// attach middleware
 	r.Use(logging)
-	r.Use(recovery)
+	if s.options.SlowThreshold > 0 {
+		r.Use(slowRequestMiddleware(s.options.SlowThreshold))
+	}
+	if s.options.HSTS && s.options.TLSAuto {
+		r.Use(hstsMiddleware)
+	}
+	if s.idle != nil {
+		r.Use(s.idle.middleware)
+	}
+	if !s.options.NoRecover {
+		r.Use(recovery)
+	}
+	if s.options.MaxBodyBytes > 0 {
+		r.Use(maxBodyMiddleware(s.options.MaxBodyBytes))
+	}
+	if len(s.headers) > 0 {
+		r.Use(customHeaders)
+	}
+	if len(s.allowedOrigins) > 0 {
+		r.Use(handlers.CORS(handlers.AllowedOrigins(s.allowedOrigins)))
+	}
+	if s.options.devOverlay != nil {
+		r.Use(s.options.devOverlay.middleware)
+	}
+
+	// The rate limiter, session log and Server header wrap the router,
+	// rather than being router middleware, so that they also apply to
+	// unmatched requests. The session log is kept in quiet mode, as it
+	// records the testing sessions rather than the server's activity.
+	var handler http.Handler = r
+	if s.limiter != nil {
+		handler = s.limiter.middleware(handler)
+	}
+	if s.options.SessionParam != "" {
+		var sessionWriter io.Writer = os.Stdout
+		if s.options.logWriter != nil {
+			sessionWriter = s.options.logWriter
+		}
+		handler = sessionLogMiddleware(s.options.SessionParam, sessionWriter)(handler)
+	}
+	if s.options.ServerHeader != nil {
+		handler = serverHeaderMiddleware(*s.options.ServerHeader)(handler)
+	}
+	return handler, nil
+}
+
+// defaultSlowThreshold is the default duration above which requests
+// are logged as slow, long enough to be silent in normal use.
+const defaultSlowThreshold = 2 * time.Second
+
+// slowRequestMiddleware logs a warning, separately from the access log,
+// for requests taking longer than threshold, such as pages with large
+// images or slow templates.
+func slowRequestMiddleware(threshold time.Duration) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			handler.ServeHTTP(w, r)
+			if elapsed := time.Since(start); elapsed > threshold {
+				log.Printf("slow request warning: %s %s took %s", r.Method, r.URL.Path, elapsed.Round(time.Millisecond))
+			}
+		})
+	}
+}
+
+// serverHeaderMiddleware sets the Server header of responses to value,
+// or removes it if value is empty.
+func serverHeaderMiddleware(value string) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if value == "" {
+				w.Header().Del("Server")
+			} else {
+				w.Header().Set("Server", value)
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxBodyMiddleware limits the size of request bodies, other than for
+// GET and HEAD requests, to limit bytes.
+func maxBodyMiddleware(limit int64) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// portScanAttempts is the number of ports after the configured port
+// tried by bindScan, such as 8001 to 8010 for port 8000.
+const portScanAttempts = 10
+
+// bindScan binds the server's listener before Serve, trying each of
+// the portScanAttempts following ports in turn if the configured port
+// is in use. The server port and address are updated to the port bound.
+func (s *server) bindScan() error {
+	port, err := strconv.Atoi(s.serverPort)
+	if err != nil {
+		return fmt.Errorf("invalid port: %s", s.serverPort)
+	}
+	for i := 0; ; i++ {
+		addr := net.JoinHostPort(s.serverAddress, strconv.Itoa(port+i))
+		ln, err := net.Listen("tcp", addr)
+		if err == nil {
+			// Take the port from the listener to resolve port 0.
+			s.listener = ln
+			s.serverPort = strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+			s.webServer.Addr = net.JoinHostPort(s.serverAddress, s.serverPort)
+			return nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) || i == portScanAttempts {
+			return fmt.Errorf("listen error: %w", err)
+		}
+	}
+}
 
-	return r, nil
+// listen constructs the server's listener explicitly so that it can be
+// wrapped to limit the number of concurrent connections. Connections
+// beyond the limit are not accepted until others close, and so wait in
+// the operating system's listen backlog. A listener bound by bindScan is
+// used if set.
+func (s *server) listen() (net.Listener, error) {
+	ln := s.listener
+	if ln == nil {
+		var err error
+		ln, err = net.Listen("tcp", s.webServer.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("listen error: %w", err)
+		}
+	}
+	if s.options.MaxConns > 0 {
+		ln = netutil.LimitListener(ln, s.options.MaxConns)
+	}
+	return ln, nil
+}
+
+// newHandler returns the http handler for the validated configuration
+// cfg without starting a server, so that the routes can be mounted in
+// another router or wrapped by other middleware.
+// Options concerning listening, such as MaxConns, TLSAuto and
+// IdleShutdown, have no effect.
+func newHandler(cfg *config, options ServerOptions) (http.Handler, error) {
+	s, err := newServer("127.0.0.1", "0", cfg, options)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildHandler()
 }
 
 // Serve starts serving the server at the configured address and port.
 func Serve(s *server) error {
 
-	var err error
-	s.webServer.Handler, err = s.buildHandler()
+	// The handler may already have been built, such as by develop to
+	// check that the server can start.
+	if !s.handler.loaded() {
+		handler, err := s.buildHandler()
+		if err != nil {
+			return fmt.Errorf("router building error: %w", err)
+		}
+		s.handler.store(handler)
+	}
+	s.webServer.Handler = &s.handler
+
+	// An injected WebServer replaces listening on a port.
+	if s.options.webServer != nil {
+		if err := s.options.webServer.ListenAndServe(); err != nil {
+			return fmt.Errorf("fatal server error: %w", err)
+		}
+		return nil
+	}
+
+	ln, err := s.listen()
 	if err != nil {
-		return fmt.Errorf("router building error: %w", err)
+		return err
+	}
+	if s.certManager != nil {
+		s.startHTTPRedirect()
+		ln = tls.NewListener(ln, s.webServer.TLSConfig)
+	}
+	if s.idle != nil {
+		done := make(chan struct{})
+		s.webServer.RegisterOnShutdown(func() { close(done) })
+		s.idle.touch()
+		go s.idle.watch(s.webServer, done)
 	}
 
-	err = s.webServer.ListenAndServe()
+	err = s.webServer.Serve(ln)
+	if s.idle != nil && s.idle.shutdown.Load() && errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("fatal server error: %w", err)
 	}
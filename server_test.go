@@ -4,18 +4,45 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"html/template"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
 )
 
 // initServer inits a server with default content in the repo, such as
 // the material at assets/static (including styles) and pages (including
 // images from assets/images and templates from assets/templates).
 func initServer(t *testing.T) *server {
+	t.Helper()
+	s, err := newServer(
+		"127.0.0.1",
+		"8001",
+		initServerConfig(t),
+		ServerOptions{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// initServerConfig returns the validated config used by initServer.
+func initServerConfig(t *testing.T) *config {
 	t.Helper()
 	cfg := &config{
 		AssetsDir:     "assets",
@@ -27,29 +54,21 @@ func initServer(t *testing.T) *server {
 				Title:     "Home",
 				ImagePath: "images/home.jpg",
 				// Note:      "",
-				Zones: []pageZone{pageZone{367, 44, 539, 263, "/detail", ""}},
+				Zones: []pageZone{pageZone{Left: 367, Top: 44, Right: 539, Bottom: 263, Target: "/detail"}},
 			},
 			page{
 				URL:       "/detail",
 				Title:     "Detail",
 				ImagePath: "images/detail.jpg",
 				Note:      "",
-				Zones:     []pageZone{pageZone{436, 31, 538, 73, "/home", ""}},
+				Zones:     []pageZone{pageZone{Left: 436, Top: 31, Right: 538, Bottom: 73, Target: "/home"}},
 			},
 		},
 	}
 	if err := cfg.validateConfig(); err != nil {
 		t.Fatal(err)
 	}
-	s, err := newServer(
-		"127.0.0.1",
-		"8001",
-		cfg,
-	)
-	if err != nil {
-		t.Fatal(err)
-	}
-	return s
+	return cfg
 }
 
 // TestServer tests a running server instance of the site using the
@@ -74,8 +93,10 @@ func TestServer(t *testing.T) {
 		bodyContains string
 	}{
 		{"Health Check", "/health", http.StatusOK, `{"status":"up"}`},
+		{"Sitemap", "/__sitemap", http.StatusOK, `"targetTitle": "Detail"`},
 		{"Home Page", "/home", http.StatusOK, "<title>Home"},
 		{"Detail Page", "/detail", http.StatusOK, "<title>Detail"},
+		{"Page Navigation", "/detail", http.StatusOK, `<li class="current"><a href="/detail">Detail</a></li>`},
 		{"Favicon", "/favicon", http.StatusOK, "<svg xmlns="},
 		{"Favicon ico", "/favicon.ico", http.StatusOK, "<svg xmlns="},
 		{"Image File", "/images/home.jpg", http.StatusOK, "Photoshop 3.0"},
@@ -133,3 +154,1432 @@ func TestServerServe(t *testing.T) {
 		t.Fatalf("unexpected error %T %v", err, err)
 	}
 }
+
+// stubWebServer is a WebServer which records ListenAndServe calls
+// without listening.
+type stubWebServer struct {
+	calls int
+	err   error
+}
+
+func (sw *stubWebServer) ListenAndServe() error {
+	sw.calls++
+	return sw.err
+}
+
+// TestServerServeWebServer checks that an injected WebServer replaces
+// listening, and that the handler is ready when it is called.
+func TestServerServeWebServer(t *testing.T) {
+	stub := &stubWebServer{err: http.ErrServerClosed}
+	s, err := newServer("127.0.0.1", "8001", initServerConfig(t), ServerOptions{webServer: stub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Serve(s)
+	if !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("got error %v want %v", err, http.ErrServerClosed)
+	}
+	if got, want := stub.calls, 1; got != want {
+		t.Errorf("got %d ListenAndServe calls want %d", got, want)
+	}
+
+	w := httptest.NewRecorder()
+	s.webServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Errorf("got status %d want %d", got, want)
+	}
+}
+
+// TestServerServeBuiltHandler checks that Serve uses a handler already
+// built for the server rather than building another.
+func TestServerServeBuiltHandler(t *testing.T) {
+	stub := &stubWebServer{err: http.ErrServerClosed}
+	s, err := newServer("127.0.0.1", "8001", initServerConfig(t), ServerOptions{webServer: stub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.handler.store(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	if err := Serve(s); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("got error %v want %v", err, http.ErrServerClosed)
+	}
+
+	w := httptest.NewRecorder()
+	s.webServer.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+	if got, want := w.Code, http.StatusTeapot; got != want {
+		t.Errorf("got status %d want %d", got, want)
+	}
+}
+
+// TestServerRateLimit checks that unmatched requests are rate limited,
+// that /health is exempt and that the limits are kept over a reload.
+func TestServerRateLimit(t *testing.T) {
+	s, err := newServer("127.0.0.1", "8001", initServerConfig(t), ServerOptions{RateLimit: 0.001, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	s.handler.store(handler)
+
+	status := func(url string) int {
+		w := httptest.NewRecorder()
+		s.handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w.Code
+	}
+	if got, want := status("/wp-login.php"), http.StatusNotFound; got != want {
+		t.Errorf("first unmatched request got %d want %d", got, want)
+	}
+	if got, want := status("/wp-login.php"), http.StatusTooManyRequests; got != want {
+		t.Errorf("second unmatched request got %d want %d", got, want)
+	}
+	if got, want := status("/health"), http.StatusOK; got != want {
+		t.Errorf("health request got %d want %d", got, want)
+	}
+
+	if err := s.reload(initServerConfig(t)); err != nil {
+		t.Fatal("reload error:", err)
+	}
+	if got, want := status("/home"), http.StatusTooManyRequests; got != want {
+		t.Errorf("request after reload got %d want %d", got, want)
+	}
+}
+
+// TestServerZoneDescription checks that zone descriptions are rendered
+// as escaped titles.
+func TestServerZoneDescription(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Zones[0].Description = `Go "there"`
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/home")
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read body: %v", err)
+	}
+	if want := `title="Go &#34;there&#34;"`; !bytes.Contains(body, []byte(want)) {
+		t.Errorf("body does not contain %q", want)
+	}
+}
+
+// TestServerZoneAltTargets checks that right and middle click targets
+// are rendered as data attributes.
+func TestServerZoneAltTargets(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Zones[0].RightTarget = "/home"
+	s.pages[0].Zones[0].MiddleTarget = "/detail"
+
+	w := httptest.NewRecorder()
+	handler, err := s.Page(&s.pages[0], s.pageTpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler(w, httptest.NewRequest("GET", "/home", nil))
+	for _, want := range []string{`data-right-target="/home"`, `data-middle-target="/detail"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+}
+
+// TestServerPageMeta checks that page metadata is rendered by the page
+// template and that values are escaped.
+func TestServerPageMeta(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Meta = map[string]string{
+		"author": "<b>someone</b>",
+		"status": "draft",
+	}
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/home")
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read body: %v", err)
+	}
+	for _, want := range []string{
+		"<dt>author</dt><dd>&lt;b&gt;someone&lt;/b&gt;</dd>",
+		"<dt>status</dt><dd>draft</dd>",
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+}
+
+// TestServerPageBackground checks that a page background is applied to
+// the page body.
+func TestServerPageBackground(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Background = "#f0f0f0"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		url  string
+		want bool
+	}{
+		{"/home", true},
+		{"/detail", false},
+	} {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read body: %v", err)
+		}
+		if got := bytes.Contains(body, []byte(`<body style="background-color: #f0f0f0;">`)); got != tt.want {
+			t.Errorf("%s background got %t want %t", tt.url, got, tt.want)
+		}
+	}
+}
+
+// TestServerPageFavicon checks that a page favicon is linked only from
+// its page.
+func TestServerPageFavicon(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Favicon = "static/favicon.svg"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		url  string
+		want bool
+	}{
+		{"/home", true},
+		{"/detail", false},
+	} {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read body: %v", err)
+		}
+		if got := bytes.Contains(body, []byte(`<link rel="icon" href="/static/favicon.svg" />`)); got != tt.want {
+			t.Errorf("%s favicon got %t want %t", tt.url, got, tt.want)
+		}
+	}
+}
+
+// TestServerPageTour checks that the previous and next tour urls are
+// provided to pages on the tour.
+func TestServerPageTour(t *testing.T) {
+	s := initServer(t)
+	s.tour = []string{"/detail", "/home"}
+
+	for _, tt := range []struct {
+		url      string
+		prev     string
+		next     string
+		wantBody string
+	}{
+		{"/detail", "", "/home", `data-tour-next="/home">`},
+		{"/home", "/detail", "", `data-tour-prev="/detail">`},
+	} {
+		i := slices.IndexFunc(s.pages, func(p page) bool { return p.URL == tt.url })
+		data := s.pageData(&s.pages[i])
+		if data.TourPrev != tt.prev || data.TourNext != tt.next {
+			t.Errorf("%s tour got %q, %q want %q, %q", tt.url, data.TourPrev, data.TourNext, tt.prev, tt.next)
+		}
+		var buf bytes.Buffer
+		if err := s.pageTpl.Execute(&buf, data); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.wantBody) {
+			t.Errorf("%s body does not contain %q", tt.url, tt.wantBody)
+		}
+	}
+}
+
+// TestServerLang checks that pages and the index are served with the
+// config language, or the page language if set, in the html lang
+// attribute and Content-Language header.
+func TestServerLang(t *testing.T) {
+	s := initServer(t)
+	s.lang = "de"
+	s.pages[0].Lang = "fr"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		url  string
+		want string
+	}{
+		{s.pages[0].URL, "fr"},
+		{s.pages[1].URL, "de"},
+		{"/index", "de"},
+	} {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read body: %v", err)
+		}
+		if got := resp.Header.Get("Content-Language"); got != tt.want {
+			t.Errorf("%s Content-Language got %q want %q", tt.url, got, tt.want)
+		}
+		if want := `<html lang="` + tt.want + `">`; !bytes.Contains(body, []byte(want)) {
+			t.Errorf("%s body does not contain %q", tt.url, want)
+		}
+	}
+}
+
+// TestServerPageAutoAdvance checks that a page auto advance is
+// rendered for the page script.
+func TestServerPageAutoAdvance(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].AutoAdvance = &autoAdvance{Target: "/detail", AfterMs: 1500}
+
+	var buf bytes.Buffer
+	if err := s.pageTpl.Execute(&buf, s.pageData(&s.pages[0])); err != nil {
+		t.Fatal(err)
+	}
+	if want := `data-auto-advance="/detail" data-auto-advance-after="1500">`; !strings.Contains(buf.String(), want) {
+		t.Errorf("body does not contain %q", want)
+	}
+}
+
+// TestServerShowZones checks that zone outlines are only rendered with
+// the ShowZones option or a zones=1 query.
+func TestServerShowZones(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		option bool
+		url    string
+		want   bool
+	}{
+		{"off", false, "/home", false},
+		{"option", true, "/home", true},
+		{"query", false, "/home?zones=1", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := initServer(t)
+			s.options.ShowZones = tt.option
+
+			handler, err := s.buildHandler()
+			if err != nil {
+				t.Fatal("buildHander error:", err)
+			}
+			r := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			body := w.Body.String()
+			if got := strings.Contains(body, "show-zones"); got != tt.want {
+				t.Errorf("show-zones class got %t want %t", got, tt.want)
+			}
+			if got := strings.Contains(body, `<span class="zone-label">/detail</span>`); got != tt.want {
+				t.Errorf("zone label got %t want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestServerIndexTargets checks that the index lists the targets of
+// each page.
+func TestServerIndexTargets(t *testing.T) {
+	s := initServer(t)
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/index", nil))
+	for _, want := range []string{`&raquo; <a href="/detail">Detail</a>`, `&raquo; <a href="/home">Home</a>`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("index does not contain %q", want)
+		}
+	}
+}
+
+// TestServerIndexPatternPages checks that pages with path variables are
+// left out of the index and page navigation.
+func TestServerIndexPatternPages(t *testing.T) {
+	cfg := initServerConfig(t)
+	cfg.Pages = append(cfg.Pages, page{URL: "/item/{id}", Title: "Item", ImagePath: "images/detail.jpg", Zones: cfg.Pages[1].Zones})
+	cfg.OrderedPages = orderPages(cfg.Pages)
+	s, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	for _, url := range []string{"/index", "/home"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if !strings.Contains(w.Body.String(), `href="/detail"`) {
+			t.Errorf("%s does not link to /detail", url)
+		}
+		if strings.Contains(w.Body.String(), "/item/") {
+			t.Errorf("%s unexpectedly links to the pattern page", url)
+		}
+	}
+}
+
+// TestServerPatternPageImage checks that the image of a page with path
+// variables is linked by an absolute path which is served.
+func TestServerPatternPageImage(t *testing.T) {
+	cfg := initServerConfig(t)
+	cfg.Pages = append(cfg.Pages, page{URL: "/item/{id}", Title: "Item", ImagePath: "images/detail.jpg", Zones: cfg.Pages[1].Zones})
+	s, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/item/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`<img src="([^"]+)"`).FindSubmatch(body)
+	if m == nil {
+		t.Fatal("page has no img element")
+	}
+	if got, want := string(m[1]), "/images/detail.jpg"; got != want {
+		t.Errorf("img src got %q want %q", got, want)
+	}
+
+	resp, err = ts.Client().Get(ts.URL + string(m[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("image status got %d want %d", got, want)
+	}
+}
+
+// TestServerLogWriter checks that the access log is written to the
+// log writer option, if set.
+func TestServerLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := newServer("127.0.0.1", "8001", initServerConfig(t), ServerOptions{logWriter: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/home", nil))
+	if !strings.Contains(buf.String(), `"GET /home HTTP/1.1" 200`) {
+		t.Errorf("access log not written, got %q", buf.String())
+	}
+}
+
+// TestServerSessionLog checks that the session log is written in quiet
+// mode, including for unmatched requests.
+func TestServerSessionLog(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := newServer("127.0.0.1", "8001", initServerConfig(t), ServerOptions{
+		Quiet:        true,
+		SessionParam: "session",
+		logWriter:    &buf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	for _, url := range []string{"/home?session=abc", "/missing?session=abc"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+	for _, want := range []string{`"path":"/home","status":200`, `"path":"/missing","status":404`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("session log does not contain %s, got %q", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "HTTP/1.1") {
+		t.Error("access log unexpectedly written in quiet mode")
+	}
+}
+
+// TestServerPageVideo checks that video pages are rendered with a video
+// element.
+func TestServerPageVideo(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].MediaType = "video"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+	if want := `<video src="/images/home.jpg" autoplay loop muted playsinline></video>`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("page does not contain %q", want)
+	}
+	if strings.Contains(w.Body.String(), "<img") {
+		t.Error("video page unexpectedly contains an img element")
+	}
+}
+
+// TestServerFaviconInline checks that inline svg favicon content is
+// served directly.
+func TestServerFaviconInline(t *testing.T) {
+	s := initServer(t)
+	s.favicon = `<svg xmlns="http://www.w3.org/2000/svg"><circle r="4"/></svg>`
+
+	w := httptest.NewRecorder()
+	s.Favicon(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+
+	if got, want := w.Header().Get("Content-Type"), "image/svg+xml"; got != want {
+		t.Errorf("content type got %q want %q", got, want)
+	}
+	if got, want := w.Body.String(), s.favicon; got != want {
+		t.Errorf("body got %q want %q", got, want)
+	}
+}
+
+// TestServerCustomHeaders checks that configured headers are set on
+// responses.
+func TestServerCustomHeaders(t *testing.T) {
+	s := initServer(t)
+	s.headers = map[string]string{
+		"Content-Security-Policy": "frame-ancestors https://example.com",
+		"X-Frame-Options":         "ALLOW-FROM https://example.com",
+	}
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, path := range []string{"/home", "/static/styles.css"} {
+		resp, err := ts.Client().Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		_ = resp.Body.Close()
+		for k, v := range s.headers {
+			if got := resp.Header.Get(k); got != v {
+				t.Errorf("%s header %s got %q want %q", path, k, got, v)
+			}
+		}
+	}
+}
+
+// TestComputeIndexPages checks that automatic index urls are only
+// provided where no page claims them.
+func TestComputeIndexPages(t *testing.T) {
+	tests := []struct {
+		name   string
+		urls   []string
+		splash string
+		want   []string
+	}{
+		{"neither", []string{"/home", "/detail"}, "", []string{"/index", "/"}},
+		{"root", []string{"/", "/detail"}, "", []string{"/index"}},
+		{"index", []string{"/index", "/detail"}, "", []string{"/"}},
+		{"both", []string{"/", "/index"}, "", []string{}},
+		{"splash", []string{"/home", "/detail"}, "templates/splash.html", []string{"/index"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config{SplashTemplate: tt.splash}
+			for _, u := range tt.urls {
+				cfg.Pages = append(cfg.Pages, page{URL: u})
+			}
+			if diff := cmp.Diff(tt.want, computeIndexPages(cfg)); diff != "" {
+				t.Errorf("index pages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestServerAssetsURL checks that with an assets url pages reference
+// images and static files externally and they are not served locally.
+func TestServerAssetsURL(t *testing.T) {
+	s := initServer(t)
+	s.assetsURL = "https://cdn.example.com"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	body := get("/home").Body.String()
+	for _, want := range []string{
+		`href="https://cdn.example.com/static/styles.css"`,
+		`src="https://cdn.example.com/images/home.jpg"`,
+		`src="https://cdn.example.com/static/zones.js"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	for _, path := range []string{"/images/home.jpg", "/static/styles.css"} {
+		if got, want := get(path).Code, http.StatusNotFound; got != want {
+			t.Errorf("%s got status %d want %d", path, got, want)
+		}
+	}
+}
+
+// TestServerBackZone checks that "back" zones are marked for the
+// zones.js history handler, with the index as the fallback href.
+func TestServerBackZone(t *testing.T) {
+	s := initServer(t)
+	s.pages[1].Zones[0].Target = backTarget
+	s.pages[1].Zones[0].Back = true
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/detail", nil))
+	for _, want := range []string{`data-back="true"`, `href="/index"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if notWant := `href="back"`; strings.Contains(w.Body.String(), notWant) {
+		t.Errorf("page unexpectedly contains %q", notWant)
+	}
+}
+
+// TestServerZoneViewport checks that zone viewports are provided as a
+// data attribute.
+func TestServerZoneViewport(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Zones[0].Viewport = viewportMobile
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+	if want := `data-viewport="mobile"`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("page does not contain %q", want)
+	}
+}
+
+// TestServerSplash checks that the splash template is served at "/"
+// with a link to the entry page, leaving the index at "/index".
+func TestServerSplash(t *testing.T) {
+	cfg := initServerConfig(t)
+	cfg.SplashTemplate = "templates/splash.html"
+	if err := cfg.validateConfig(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{Precompile: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s got status %d", path, w.Code)
+		}
+		return w.Body.String()
+	}
+	if want := `<a class="start" href="/home">Start</a>`; !strings.Contains(get("/"), want) {
+		t.Errorf("splash does not contain %q", want)
+	}
+	if want := "<h1>Index</h1>"; !strings.Contains(get("/index"), want) {
+		t.Errorf("index does not contain %q", want)
+	}
+}
+
+// TestServerErrorTemplate checks that page template execution errors
+// are rendered with the error template and a 500 status, falling back
+// to a plain text error without one.
+func TestServerErrorTemplate(t *testing.T) {
+	failing := template.Must(template.New("fail").Parse(`{{ .Nope }}`))
+	for _, withTemplate := range []bool{true, false} {
+		cfg := initServerConfig(t)
+		if withTemplate {
+			cfg.ErrorTemplate = "templates/error.html"
+		}
+		if err := cfg.validateConfig(); err != nil {
+			t.Fatal(err)
+		}
+		s, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.pageTpl = failing
+		handler, err := s.buildHandler()
+		if err != nil {
+			t.Fatal("buildHander error:", err)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/home", nil))
+		if got, want := w.Code, http.StatusInternalServerError; got != want {
+			t.Errorf("template %t got status %d want %d", withTemplate, got, want)
+		}
+		body := w.Body.String()
+		if got, want := strings.Contains(body, "<h1>Something went wrong</h1>"), withTemplate; got != want {
+			t.Errorf("template %t error page rendered %t", withTemplate, got)
+		}
+		if !strings.Contains(body, "evaluate field Nope") {
+			t.Errorf("template %t body does not contain the error: %s", withTemplate, body)
+		}
+	}
+}
+
+// TestServerOverlayZone checks that overlay zones are marked for the
+// zones.js overlay handler.
+func TestServerOverlayZone(t *testing.T) {
+	s := initServer(t)
+	s.pages[1].Zones[0].Target = "#details"
+	s.pages[1].Zones[0].Overlay = "details"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/detail", nil))
+	for _, want := range []string{`href="#details"`, `data-overlay="details"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+}
+
+// TestServerRedirects checks that legacy paths are permanently
+// redirected to their pages.
+func TestServerRedirects(t *testing.T) {
+	s := initServer(t)
+	s.redirects = map[string]string{"/old-home": "/home"}
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	r := httptest.NewRequest("GET", "/old-home", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusMovedPermanently; got != want {
+		t.Errorf("got status %d want %d", got, want)
+	}
+	if got, want := w.Header().Get("Location"), "/home"; got != want {
+		t.Errorf("got location %q want %q", got, want)
+	}
+}
+
+// TestServerCORS checks that the request origin is echoed only if it is
+// allowed, and that preflight requests are answered.
+func TestServerCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    string
+	}{
+		{"no cors", nil, "https://example.com", ""},
+		{"allowed", []string{"https://example.com"}, "https://example.com", "https://example.com"},
+		{"not allowed", []string{"https://example.com"}, "https://other.com", ""},
+		{"wildcard", []string{"*"}, "https://other.com", "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := initServer(t)
+			s.allowedOrigins = tt.allowed
+			s.options.Quiet = true
+			handler, err := s.buildHandler()
+			if err != nil {
+				t.Fatal("buildHander error:", err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/home", nil)
+			r.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("get origin got %q want %q", got, tt.want)
+			}
+
+			r = httptest.NewRequest(http.MethodOptions, "/home", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("preflight origin got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestServerCustomDirs checks that configured asset directory names,
+// including nested ones, are mounted at their paths in place of the
+// defaults.
+func TestServerCustomDirs(t *testing.T) {
+	assetsDir := t.TempDir()
+	for src, dst := range map[string]string{
+		"assets/images/home.jpg":      "img/home.jpg",
+		"assets/images/detail.jpg":    "img/detail.jpg",
+		"assets/static/favicon.svg":   "web/css/favicon.svg",
+		"assets/static/styles.css":    "web/css/styles.css",
+		"assets/templates/page.html":  "tpl/page.html",
+		"assets/templates/index.html": "tpl/index.html",
+	} {
+		b, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst = filepath.Join(assetsDir, dst)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config{
+		AssetsDir:     assetsDir,
+		PageTemplate:  "tpl/page.html",
+		IndexTemplate: "tpl/index.html",
+		Dirs:          assetDirs{Images: "img", Static: "web/css", Templates: "tpl"},
+		Pages: []page{
+			page{
+				URL:       "/home",
+				Title:     "Home",
+				ImagePath: "img/home.jpg",
+				Zones:     []pageZone{pageZone{Left: 367, Top: 44, Right: 539, Bottom: 263, Target: "/detail"}},
+			},
+			page{
+				URL:       "/detail",
+				Title:     "Detail",
+				ImagePath: "img/detail.jpg",
+				Zones:     []pageZone{pageZone{Left: 436, Top: 31, Right: 538, Bottom: 73, Target: "/home"}},
+			},
+		},
+	}
+	ts := NewTestServer(t, cfg)
+
+	for path, want := range map[string]int{
+		"/img/home.jpg":        http.StatusOK,
+		"/web/css/styles.css":  http.StatusOK,
+		"/web/css/favicon.svg": http.StatusOK,
+		"/favicon":             http.StatusOK,
+		"/css/styles.css":      http.StatusNotFound,
+		"/tpl/":                http.StatusNotFound,
+		"/images/home.jpg":     http.StatusNotFound,
+	} {
+		resp, err := ts.Client().Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if got := resp.StatusCode; got != want {
+			t.Errorf("%s got %d want %d", path, got, want)
+		}
+	}
+
+	// the page links the stylesheet and zone script from the static
+	// directory
+	resp, err := ts.Client().Get(ts.URL + "/home")
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`href="/web/css/styles.css"`, `src="/web/css/zones.js"`, `src="/img/home.jpg"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+}
+
+// TestServerH2C checks that the server speaks unencrypted HTTP/2 when
+// the h2c option is set.
+func TestServerH2C(t *testing.T) {
+	cfg, err := newConfig([]byte(makeOKConfig(t, false)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newServer("127.0.0.1", "0", cfg, ServerOptions{H2C: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.webServer.Handler, err = s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = s.webServer.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		_ = s.webServer.Close()
+	})
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	resp, err := client.Get("http://" + ln.Addr().String() + "/health")
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got, want := resp.ProtoMajor, 2; got != want {
+		t.Errorf("protocol major version got %d want %d", got, want)
+	}
+}
+
+// TestMaxBodyMiddleware checks that non-GET request bodies are limited.
+func TestMaxBodyMiddleware(t *testing.T) {
+	reader := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := maxBodyMiddleware(10)(reader)
+
+	tests := []struct {
+		method string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, "small", http.StatusOK},
+		{http.MethodPost, "much too large a body", http.StatusRequestEntityTooLarge},
+		{http.MethodGet, "much too large a body", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+		if got := w.Code; got != tt.want {
+			t.Errorf("%s %q got %d want %d", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+// TestSlowRequestMiddleware checks that only requests slower than the
+// threshold are logged.
+func TestSlowRequestMiddleware(t *testing.T) {
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	handler := slowRequestMiddleware(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+	}))
+	for _, path := range []string{"/fast", "/slow"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if got := logBuf.String(); !strings.Contains(got, "slow request warning: GET /slow took") || strings.Contains(got, "/fast") {
+		t.Errorf("unexpected slow request log %q", got)
+	}
+}
+
+// TestServerNoRecover checks that panics are only recovered by the
+// recovery middleware if NoRecover is not set.
+func TestServerNoRecover(t *testing.T) {
+	for _, noRecover := range []bool{false, true} {
+		s := initServer(t)
+		s.options.NoRecover = noRecover
+		s.options.Quiet = true
+
+		handler, err := s.buildHandler()
+		if err != nil {
+			t.Fatal("buildHander error:", err)
+		}
+		handler.(*mux.Router).HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})
+
+		func() {
+			defer func() {
+				if got := recover() != nil; got != noRecover {
+					t.Errorf("noRecover %t: panicked %t", noRecover, got)
+				}
+			}()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+			if got, want := w.Code, http.StatusInternalServerError; got != want {
+				t.Errorf("status got %d want %d", got, want)
+			}
+		}()
+	}
+}
+
+func TestServerHealthAccept(t *testing.T) {
+	s := initServer(t)
+
+	tests := []struct {
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{"", "application/json; charset=utf-8", `{"status":"up"}` + "\n"},
+		{"*/*", "application/json; charset=utf-8", `{"status":"up"}` + "\n"},
+		{"text/plain", "text/plain; charset=utf-8", "up\n"},
+		{"text/*", "text/plain; charset=utf-8", "up\n"},
+		{"application/json, text/plain;q=0.5", "application/json; charset=utf-8", `{"status":"up"}` + "\n"},
+		{"application/json;q=0.2, text/plain", "text/plain; charset=utf-8", "up\n"},
+		{"text/plain, */*;q=0.1", "text/plain; charset=utf-8", "up\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/health", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			s.Health(w, r)
+			if got, want := w.Header().Get("Content-Type"), tt.wantContentType; got != want {
+				t.Errorf("content type got %q want %q", got, want)
+			}
+			if got, want := w.Body.String(), tt.wantBody; got != want {
+				t.Errorf("body got %q want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestServerRequestTimeout(t *testing.T) {
+	s := initServer(t)
+	s.options.RequestTimeout = time.Nanosecond
+
+	slow := s.withTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	slow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/home", nil))
+	if got, want := w.Code, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status got %d want %d", got, want)
+	}
+	if got, want := w.Body.String(), requestTimeoutMessage; got != want {
+		t.Errorf("body got %q want %q", got, want)
+	}
+
+	// static files are exempt
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/styles.css", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Errorf("static status got %d want %d", got, want)
+	}
+}
+
+func TestServerPprof(t *testing.T) {
+	for _, tt := range []struct {
+		pprof bool
+		want  int
+	}{
+		{false, http.StatusNotFound},
+		{true, http.StatusOK},
+	} {
+		s := initServer(t)
+		s.options.Pprof = tt.pprof
+		s.options.Quiet = true
+		handler, err := s.buildHandler()
+		if err != nil {
+			t.Fatal("buildHander error:", err)
+		}
+		for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"} {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if got := w.Code; got != tt.want {
+				t.Errorf("pprof %t %s got %d want %d", tt.pprof, path, got, tt.want)
+			}
+		}
+	}
+}
+
+// TestServerPprofProfile checks that a cpu profile running for longer
+// than the server write timeout is returned.
+func TestServerPprofProfile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the 3s cpu profile in short mode")
+	}
+	s := initServer(t)
+	s.options.Pprof = true
+	s.options.Quiet = true
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config.WriteTimeout = s.webServer.WriteTimeout
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/debug/pprof/profile?seconds=3")
+	if err != nil {
+		t.Fatalf("profile error: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read profile: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Errorf("profile got status %d with %d bytes", resp.StatusCode, len(body))
+	}
+}
+
+// TestServerPageParams checks that the path variables of parameterized
+// page urls are provided to the page template as .Params.
+func TestServerPageParams(t *testing.T) {
+	s := initServer(t)
+	s.options.Quiet = true
+	p := s.pages[0]
+	p.URL = "/item/{id}"
+
+	tpl := template.Must(template.New("params").Parse("item {{ .Params.id }}"))
+	pe, err := s.Page(&p, tpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.HandleFunc(p.URL, pe)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/item/42", nil))
+	if got, want := w.Body.String(), "item 42"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+// TestServerMaxConns checks that connections beyond the limit are not
+// accepted until an earlier connection closes.
+func TestServerMaxConns(t *testing.T) {
+	s := initServer(t)
+	s.webServer.Addr = "127.0.0.1:0"
+	s.options.MaxConns = 1
+
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	for range 2 {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = c.Close() }()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("second connection accepted beyond the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_ = first.Close()
+	select {
+	case c := <-accepted:
+		_ = c.Close()
+	case <-time.After(time.Second):
+		t.Fatal("second connection not accepted after the first closed")
+	}
+}
+
+// TestServerIndexRouteOrder checks that the "/" index is served even
+// with a static prefix which would capture it, the prefix still serving
+// other paths.
+func TestServerIndexRouteOrder(t *testing.T) {
+	s := initServer(t)
+	s.staticPath = "/"
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"/", "<h1>Index</h1>"},
+		{"/index", "<h1>Index</h1>"},
+		{"/styles.css", "body"},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s got status %d", tt.path, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), tt.want) {
+			t.Errorf("%s does not contain %q", tt.path, tt.want)
+		}
+	}
+}
+
+// TestServerCachePages checks that pages are served from the page
+// cache, except for zones queries which change the rendering.
+func TestServerCachePages(t *testing.T) {
+	s := initServer(t)
+	s.options.CachePages = true
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	if got, want := len(s.pageCache), len(s.pages); got != want {
+		t.Fatalf("got %d cached pages want %d", got, want)
+	}
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s got status %d", path, w.Code)
+		}
+		return w.Body.String()
+	}
+	if got, want := get("/home"), string(s.pageCache["/home"]); got != want {
+		t.Errorf("page does not match the cache:\n%s", got)
+	}
+
+	// Mark the cached html to show it, rather than a render, is served.
+	marker := "<!-- cached -->"
+	copy(s.pageCache["/home"], marker)
+	if got := get("/home"); !strings.HasPrefix(got, marker) {
+		t.Errorf("page not served from the cache:\n%s", got)
+	}
+	if got := get("/home?zones=1"); strings.HasPrefix(got, marker) || !strings.Contains(got, "show-zones") {
+		t.Errorf("zones page served from the cache:\n%s", got)
+	}
+}
+
+// TestServerHeader checks that the Server header is set, or removed,
+// on all responses, including images and unmatched paths.
+func TestServerHeader(t *testing.T) {
+	for _, value := range []string{"MyProto/1.0", ""} {
+		s := initServer(t)
+		s.options.ServerHeader = &value
+		handler, err := s.buildHandler()
+		if err != nil {
+			t.Fatal("buildHander error:", err)
+		}
+		for _, path := range []string{"/home", "/images/home.jpg", "/missing"} {
+			w := httptest.NewRecorder()
+			w.Header().Set("Server", "proxy")
+			handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if got := w.Header().Get("Server"); got != value {
+				t.Errorf("%s Server header got %q want %q", path, got, value)
+			}
+		}
+	}
+}
+
+// TestServerBindScan checks that bindScan moves on from a port in use.
+func TestServerBindScan(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = busy.Close() }()
+	_, busyPort, _ := net.SplitHostPort(busy.Addr().String())
+
+	s, err := newServer("127.0.0.1", busyPort, initServerConfig(t), ServerOptions{PortScan: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.bindScan(); err != nil {
+		t.Fatal("bindScan error:", err)
+	}
+	defer func() { _ = s.listener.Close() }()
+
+	got, _ := strconv.Atoi(s.serverPort)
+	want, _ := strconv.Atoi(busyPort)
+	if got <= want || got > want+portScanAttempts {
+		t.Errorf("got port %d want one of the %d ports after %d", got, portScanAttempts, want)
+	}
+	if got, want := s.listener.Addr().String(), s.webServer.Addr; got != want {
+		t.Errorf("listener addr %q does not match server addr %q", got, want)
+	}
+}
+
+func TestServerZoneGroup(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Zones[0].Group = "back"
+	s.pages[0].Zones[0].GroupClass = "back-btn"
+	s.pages[0].Zones[0].Class = "primary-action"
+	s.pages[0].Zones[0].Transition = "slide-right"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/home")
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read body: %v", err)
+	}
+	for _, want := range []string{
+		`class="clickable-zone back-btn primary-action"`,
+		`data-group="back"`,
+		`data-transition="slide-right"`,
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+}
+
+func TestServerPrecompile(t *testing.T) {
+	cfg := initServerConfig(t)
+	if _, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{Precompile: true}); err != nil {
+		t.Fatalf("unexpected precompile error: %v", err)
+	}
+
+	cfg.PageTpl = template.Must(template.New("bad").Parse("{{ .Page.Missing }}"))
+	_, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{Precompile: true})
+	if err == nil || !strings.Contains(err.Error(), "page /home template error") {
+		t.Errorf("expected page template error, got %v", err)
+	}
+
+	// without precompile the error is only found on request
+	if _, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestNewHandler checks that the handler returned by newHandler can be
+// mounted in another router.
+func TestNewHandler(t *testing.T) {
+	handler, err := newHandler(initServerConfig(t), ServerOptions{})
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/proto/", http.StripPrefix("/proto", handler))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		url    string
+		status int
+	}{
+		{"/proto/home", http.StatusOK},
+		{"/proto/health", http.StatusOK},
+		{"/home", http.StatusNotFound},
+	} {
+		resp, err := ts.Client().Get(ts.URL + tt.url)
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		_ = resp.Body.Close()
+		if got, want := resp.StatusCode, tt.status; got != want {
+			t.Errorf("%s status got %d want %d", tt.url, got, want)
+		}
+	}
+
+	if _, err := newHandler(initServerConfig(t), ServerOptions{RateLimit: -1}); err == nil {
+		t.Error("expected an invalid options error")
+	}
+}
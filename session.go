@@ -0,0 +1,65 @@
+package main
+
+// session logs the requests of a tester's session, identified by a
+// query parameter such as "?session=abc" on the entry url, as JSON
+// lines alongside the access log, so that each tester's journey
+// through a prototype can be reconstructed from the log.
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+)
+
+// sessionCookie carries the session, once given in the query, to the
+// requests following it.
+const sessionCookie = "firstgo_session"
+
+// sessionLogEntry is the JSON log line of a request in a session.
+type sessionLogEntry struct {
+	Time    string `json:"time"`
+	Session string `json:"session"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Status  int    `json:"status"`
+}
+
+// sessionLogMiddleware logs requests with a session to w as JSON
+// lines. The session is taken from the query parameter param, which
+// also sets the session cookie, or otherwise from the cookie. Requests
+// without a session are not logged.
+func sessionLogMiddleware(param string, w io.Writer) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			session := r.URL.Query().Get(param)
+			if session != "" {
+				http.SetCookie(rw, &http.Cookie{
+					Name:     sessionCookie,
+					Value:    session,
+					Path:     "/",
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			} else if c, err := r.Cookie(sessionCookie); err == nil {
+				session = c.Value
+			}
+			if session == "" {
+				handler.ServeHTTP(rw, r)
+				return
+			}
+			start := time.Now()
+			m := httpsnoop.CaptureMetrics(handler, rw, r)
+			b, _ := json.Marshal(sessionLogEntry{
+				Time:    start.UTC().Format(time.RFC3339),
+				Session: session,
+				Method:  r.Method,
+				Path:    r.URL.Path,
+				Status:  m.Code,
+			})
+			_, _ = w.Write(append(b, '\n'))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSessionLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sessionLogMiddleware("session", &buf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+
+	// The query sets the session cookie.
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/home?session=abc", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || cookies[0].Value != "abc" {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	// Later requests take the session from the cookie.
+	r := httptest.NewRequest("GET", "/missing", nil)
+	r.AddCookie(cookies[0])
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	// Requests without a session are not logged.
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/detail", nil))
+
+	var got []sessionLogEntry
+	for line := range strings.Lines(buf.String()) {
+		var e sessionLogEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("log line %q error: %v", line, err)
+		}
+		e.Time = ""
+		got = append(got, e)
+	}
+	want := []sessionLogEntry{
+		{Session: "abc", Method: "GET", Path: "/home", Status: http.StatusOK},
+		{Session: "abc", Method: "GET", Path: "/missing", Status: http.StatusNotFound},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("log mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+// sitemap provides a machine-readable JSON description of the site
+// structure and its zones from a validated configuration.
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// sitemapZone is the JSON representation of a pageZone.
+type sitemapZone struct {
+	Left         int    `json:"left"`
+	Top          int    `json:"top"`
+	Right        int    `json:"right"`
+	Bottom       int    `json:"bottom"`
+	Target       string `json:"target"`
+	TargetTitle  string `json:"targetTitle"`
+	External     bool   `json:"external,omitempty"`
+	RightTarget  string `json:"rightTarget,omitempty"`
+	MiddleTarget string `json:"middleTarget,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// sitemapPage is the JSON representation of a page.
+type sitemapPage struct {
+	URL       string            `json:"url"`
+	Title     string            `json:"title"`
+	ImagePath string            `json:"imagePath"`
+	Note      string            `json:"note,omitempty"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Zones     []sitemapZone     `json:"zones"`
+}
+
+// newSitemap converts validated pages to their JSON representation.
+func newSitemap(pages []page) []sitemapPage {
+	sm := make([]sitemapPage, 0, len(pages))
+	for _, p := range pages {
+		sp := sitemapPage{
+			URL:       p.URL,
+			Title:     p.Title,
+			ImagePath: p.ImagePath,
+			Note:      p.Note,
+			Meta:      p.Meta,
+			Zones:     newSitemapZones(p.Zones),
+		}
+		sm = append(sm, sp)
+	}
+	return sm
+}
+
+// newSitemapZones converts validated zones to their JSON
+// representation.
+func newSitemapZones(zones []pageZone) []sitemapZone {
+	sz := make([]sitemapZone, 0, len(zones))
+	for _, z := range zones {
+		sz = append(sz, sitemapZone{
+			Left:         z.Left,
+			Top:          z.Top,
+			Right:        z.Right,
+			Bottom:       z.Bottom,
+			Target:       z.Target,
+			TargetTitle:  z.TargetTitle,
+			External:     z.External,
+			RightTarget:  z.RightTarget,
+			MiddleTarget: z.MiddleTarget,
+			Description:  z.Description,
+		})
+	}
+	return sz
+}
+
+// writeSitemap writes the indented JSON sitemap of pages to w.
+func writeSitemap(w io.Writer, pages []page) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newSitemap(pages))
+}
+
+// Zones serves the JSON representation of the zones of each page,
+// keyed by page URL.
+func (s *server) Zones(w http.ResponseWriter, r *http.Request) {
+	zones := map[string][]sitemapZone{}
+	for _, p := range s.pages {
+		zones[p.URL] = newSitemapZones(p.Zones)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(zones); err != nil {
+		log.Print("zones error: unable to encode response")
+	}
+}
+
+// Sitemap serves the JSON sitemap.
+func (s *server) Sitemap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := writeSitemap(w, s.pages); err != nil {
+		log.Print("sitemap error: unable to encode response")
+	}
+}
+
+// sitemapNote is the raw and rendered markdown note of a page.
+type sitemapNote struct {
+	Note     string `json:"note,omitempty"`
+	NoteHTML string `json:"noteHTML,omitempty"`
+}
+
+// Note serves the JSON representation of the raw and rendered note of
+// the page at /__note/{url}, for example for a presenter view. Pages
+// without a note are served as an empty object.
+func (s *server) Note(w http.ResponseWriter, r *http.Request) {
+	url := "/" + mux.Vars(r)["url"]
+	ii, ok := s.pagesByURL[url]
+	if !ok {
+		s.FourOhFour("page not found")(w, r)
+		return
+	}
+	pg := s.pages[ii]
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	note := sitemapNote{Note: pg.Note, NoteHTML: string(pg.NoteHTML)}
+	if err := json.NewEncoder(w).Encode(note); err != nil {
+		log.Print("note error: unable to encode response")
+	}
+}
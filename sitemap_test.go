@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSitemap(t *testing.T) {
+	s := initServer(t)
+
+	w := httptest.NewRecorder()
+	s.Sitemap(w, httptest.NewRequest("GET", "/__sitemap", nil))
+
+	if got, want := w.Header().Get("Content-Type"), "application/json; charset=utf-8"; got != want {
+		t.Errorf("content type got %q want %q", got, want)
+	}
+
+	var got []sitemapPage
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	want := []sitemapPage{
+		{
+			URL:       "/home",
+			Title:     "Home",
+			ImagePath: "images/home.jpg",
+			Zones:     []sitemapZone{{Left: 367, Top: 44, Right: 539, Bottom: 263, Target: "/detail", TargetTitle: "Detail"}},
+		},
+		{
+			URL:       "/detail",
+			Title:     "Detail",
+			ImagePath: "images/detail.jpg",
+			Zones:     []sitemapZone{{Left: 436, Top: 31, Right: 538, Bottom: 73, Target: "/home", TargetTitle: "Home"}},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("got - want +: %v", diff)
+	}
+}
+
+func TestWriteSitemapExcludesRuntimeState(t *testing.T) {
+	var buf bytes.Buffer
+	pages := []page{{URL: "/a", Title: "A", Note: "**a**", NoteHTML: "<p><strong>a</strong></p>"}}
+	if err := writeSitemap(&buf, pages); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("<strong>")) {
+		t.Errorf("sitemap includes rendered note html: %s", buf.String())
+	}
+}
+
+func TestZones(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Zones[0].Description = "Go to the detail page"
+
+	w := httptest.NewRecorder()
+	s.Zones(w, httptest.NewRequest("GET", "/__zones", nil))
+
+	var got map[string][]sitemapZone
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if got, want := len(got), 2; got != want {
+		t.Fatalf("pages got %d want %d", got, want)
+	}
+	if got, want := got["/home"][0].Description, "Go to the detail page"; got != want {
+		t.Errorf("description got %q want %q", got, want)
+	}
+}
+
+func TestNote(t *testing.T) {
+	s := initServer(t)
+	s.pages[0].Note = "a *note*"
+	s.pages[0].NoteHTML = "<p>a <em>note</em></p>"
+
+	handler, err := s.buildHandler()
+	if err != nil {
+		t.Fatal("buildHander error:", err)
+	}
+
+	tests := []struct {
+		url    string
+		status int
+		want   sitemapNote
+	}{
+		{"/__note/home", http.StatusOK, sitemapNote{Note: "a *note*", NoteHTML: "<p>a <em>note</em></p>"}},
+		{"/__note/detail", http.StatusOK, sitemapNote{}},
+		{"/__note/missing", http.StatusNotFound, sitemapNote{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+			if got, want := w.Code, tt.status; got != want {
+				t.Fatalf("status got %d want %d", got, want)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got sitemapNote
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("note mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+// storyboard exports the pages of a prototype, with their zones drawn
+// and labelled with their targets, for offline design reviews. The
+// pages are drawn by a storyboardWriter, such as the pdfWriter.
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io/fs"
+)
+
+// storyboardFallbackWidth and Height are the page size used for media which cannot
+// be decoded, such as videos and svg images.
+const (
+	storyboardFallbackWidth  = 800
+	storyboardFallbackHeight = 600
+)
+
+// storyboardWriter draws the pages of a storyboard.
+type storyboardWriter interface {
+	// Page starts a page titled title for an image of width by height
+	// pixels. img is the encoded image and format its image package
+	// format name, such as "jpeg" or "png", or "" if the image could
+	// not be decoded, in which case the page is left blank.
+	Page(title string, width, height int, img []byte, format string) error
+	// Zone draws a zone on the current page with its target label.
+	Zone(left, top, right, bottom int, label string)
+	// Close finishes the storyboard.
+	Close() error
+}
+
+// writeStoryboard draws each page of cfg, in index order, with its
+// zones and their targets, to sw and closes it.
+func writeStoryboard(cfg *config, sw storyboardWriter) error {
+	for _, pg := range cfg.OrderedPages {
+		img, err := fs.ReadFile(cfg.AssetsFS, pg.ImagePath)
+		if err != nil {
+			return fmt.Errorf("storyboard image error: %w", err)
+		}
+		width, height := pg.ImageWidth, pg.ImageHeight
+		format := ""
+		if pg.MediaType != mediaVideo {
+			if _, f, err := image.DecodeConfig(bytes.NewReader(img)); err == nil {
+				format = f
+			}
+		}
+		if format == "" || width == 0 || height == 0 {
+			width, height, format = storyboardFallbackWidth, storyboardFallbackHeight, ""
+		}
+		if err := sw.Page(fmt.Sprintf("%s (%s)", pg.Title, pg.URL), width, height, img, format); err != nil {
+			return err
+		}
+		for _, zo := range pg.Zones {
+			sw.Zone(zo.Left, zo.Top, zo.Right, zo.Bottom, storyboardLabel(zo))
+		}
+	}
+	return sw.Close()
+}
+
+// storyboardLabel returns the label of zone zo: its target title and,
+// for page targets, the target url.
+func storyboardLabel(zo pageZone) string {
+	if zo.TargetTitle == "" || zo.TargetTitle == zo.Target {
+		return zo.Target
+	}
+	if zo.Back {
+		return zo.TargetTitle
+	}
+	return fmt.Sprintf("%s (%s)", zo.TargetTitle, zo.Target)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// stubStoryboard records the storyboard pages and zones drawn.
+type stubStoryboard struct {
+	calls  []string
+	closed bool
+}
+
+func (s *stubStoryboard) Page(title string, width, height int, img []byte, format string) error {
+	s.calls = append(s.calls, fmt.Sprintf("page %s %dx%d %s", title, width, height, format))
+	return nil
+}
+
+func (s *stubStoryboard) Zone(left, top, right, bottom int, label string) {
+	s.calls = append(s.calls, fmt.Sprintf("zone %d,%d,%d,%d %s", left, top, right, bottom, label))
+}
+
+func (s *stubStoryboard) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestWriteStoryboard(t *testing.T) {
+	cfg := initServerConfig(t)
+	stub := &stubStoryboard{}
+	if err := writeStoryboard(cfg, stub); err != nil {
+		t.Fatal(err)
+	}
+	home, detail := cfg.Pages[0], cfg.Pages[1]
+	want := []string{
+		fmt.Sprintf("page Home (/home) %dx%d jpeg", home.ImageWidth, home.ImageHeight),
+		"zone 367,44,539,263 Detail (/detail)",
+		fmt.Sprintf("page Detail (/detail) %dx%d jpeg", detail.ImageWidth, detail.ImageHeight),
+		"zone 436,31,538,73 Home (/home)",
+	}
+	if diff := cmp.Diff(want, stub.calls); diff != "" {
+		t.Errorf("storyboard mismatch (-want +got):\n%s", diff)
+	}
+	if !stub.closed {
+		t.Error("storyboard not closed")
+	}
+}
+
+// TestPDFWriter checks the structure of the PDF: that each cross
+// reference table offset locates its object, and the page count.
+func TestPDFWriter(t *testing.T) {
+	cfg := initServerConfig(t)
+	var buf bytes.Buffer
+	if err := writeStoryboard(cfg, newPDFWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(b, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(b)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(b[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref does not locate the xref table: %q", lines[0])
+	}
+	var n int
+	if _, err := fmt.Sscanf(lines[1], "0 %d", &n); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < n; i++ {
+		off, _ := strconv.Atoi(lines[2+i][:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(b[off:], []byte(want)) {
+			t.Errorf("xref offset %d does not locate object %d", off, i)
+		}
+	}
+	if !bytes.Contains(b, []byte("/Count 2 >>")) {
+		t.Error("expected 2 pages")
+	}
+	if got, want := pdfText(`a (b) \ é →`), `a \(b\) \\ \351 ?`; got != want {
+		t.Errorf("pdf text got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+// tarfs reads a tar stream of a whole project (config and assets) into
+// an in-memory filesystem, so that ephemeral demos, such as CI
+// artifacts, can be validated and served without writing to disk.
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// tarEntry is a regular file or directory read from a tar stream.
+type tarEntry struct {
+	dir     bool
+	data    []byte
+	modTime time.Time
+}
+
+// readTarFS reads the regular files and directories of the tar stream
+// r into an in-memory filesystem. The entries are stored, without
+// compression, in an in-memory zip archive, as the archive/zip reader
+// provides an fs.FS, including the directories of the files.
+func readTarFS(r io.Reader) (fs.FS, error) {
+	entries := map[string]tarEntry{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tar read error: %w", err)
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("tar entry %q has an invalid path", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			entries[name] = tarEntry{dir: true, modTime: hdr.ModTime}
+		case tar.TypeReg:
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("tar read error for %q: %w", hdr.Name, err)
+			}
+			entries[name] = tarEntry{data: b, modTime: hdr.ModTime}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("tar stream is empty")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		e := entries[name]
+		fh := &zip.FileHeader{Name: name, Method: zip.Store, Modified: e.modTime}
+		if e.dir {
+			fh.Name += "/"
+		}
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			return nil, fmt.Errorf("tar fs error for %q: %w", name, err)
+		}
+		if _, err := w.Write(e.data); err != nil {
+			return nil, fmt.Errorf("tar fs error for %q: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("tar fs error: %w", err)
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// tarProject locates the config file configName in fsys, either at
+// its root or, for tar streams of a project directory, in the
+// shallowest directory containing it. The config content and the
+// project filesystem rooted at its directory are returned.
+func tarProject(fsys fs.FS, configName string) ([]byte, fs.FS, error) {
+	configName = path.Clean(configName)
+	if b, err := fs.ReadFile(fsys, configName); err == nil {
+		return b, fsys, nil
+	}
+	var found string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, "/"+configName) {
+			return nil
+		}
+		if found == "" || strings.Count(p, "/") < strings.Count(found, "/") {
+			found = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if found == "" {
+		return nil, nil, fmt.Errorf("config file %q not found in tar stream", configName)
+	}
+	b, err := fs.ReadFile(fsys, found)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir := strings.TrimSuffix(found, "/"+configName)
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, sub, nil
+}
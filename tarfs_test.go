@@ -0,0 +1,101 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// makeProjectTar returns a tar of the repo's config.yaml and assets
+// directory with the file names prefixed by prefix.
+func makeProjectTar(t *testing.T, prefix string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	add := func(name string, b []byte) {
+		hdr := &tar.Header{Name: path.Join(prefix, name), Mode: 0644, Size: int64(len(b)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(ConfigFileName, configYaml)
+	err := fs.WalkDir(os.DirFS("."), AssetDirName, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		add(p, b)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestTarProject(t *testing.T) {
+	for _, prefix := range []string{"", "./", "project"} {
+		t.Run(prefix, func(t *testing.T) {
+			tarFS, err := readTarFS(makeProjectTar(t, prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			configBytes, projectFS, err := tarProject(tarFS, ConfigFileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := newConfigFS(configBytes, projectFS, configOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(cfg.Pages), 3; got < want {
+				t.Errorf("pages got %d want at least %d", got, want)
+			}
+		})
+	}
+
+	tarFS, err := readTarFS(makeProjectTar(t, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tarProject(tarFS, "missing.yaml"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected config not found error, got %v", err)
+	}
+
+	if _, err := readTarFS(&bytes.Buffer{}); err == nil {
+		t.Error("expected empty tar stream error")
+	}
+}
+
+func TestAppServeTar(t *testing.T) {
+	var served *server
+	app := &App{
+		stdin: makeProjectTar(t, "project"),
+		serveFunc: func(s *server) error {
+			served = s
+			return nil
+		},
+	}
+	if err := app.ServeTar("127.0.0.1", "8000", "-", ConfigFileName, ServerOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if served == nil {
+		t.Fatal("server not served")
+	}
+	if _, err := fs.Stat(served.assetsFS, "images/home.jpg"); err != nil {
+		t.Errorf("expected image in the in-memory assets: %v", err)
+	}
+}
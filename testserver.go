@@ -0,0 +1,73 @@
+package main
+
+// testserver provides a helper for running an in-process firstgo
+// server in tests without needing to replicate the server set up.
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TestingT is the subset of testing.TB used by NewTestServer. Using an
+// interface keeps the testing package out of non-test builds.
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...any)
+	Cleanup(func())
+}
+
+// TestHTTPServer is a firstgo server listening on a local port for tests,
+// in the manner of httptest.Server, which is kept out of non-test
+// builds.
+type TestHTTPServer struct {
+	URL string // base url, such as "http://127.0.0.1:54321"
+
+	server *http.Server
+	client *http.Client
+}
+
+// Client returns an http client for making requests to the server.
+func (ts *TestHTTPServer) Client() *http.Client {
+	return ts.client
+}
+
+// Close shuts down the server, closing its connections.
+func (ts *TestHTTPServer) Close() {
+	_ = ts.server.Close()
+}
+
+// NewTestServer validates cfg and returns a running TestHTTPServer using
+// the handler returned by newHandler. The server is closed when the test
+// completes. cfg should not have been validated already.
+func NewTestServer(t TestingT, cfg *config) *TestHTTPServer {
+	t.Helper()
+	if err := cfg.validateConfig(); err != nil {
+		t.Fatalf("config validation error: %v", err)
+	}
+	handler, err := newHandler(cfg, ServerOptions{})
+	if err != nil {
+		t.Fatalf("handler build error: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("test server listen error: %v", err)
+	}
+	ts := &TestHTTPServer{
+		URL: "http://" + ln.Addr().String(),
+		server: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	go func() {
+		if err := ts.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("test server error: %v", err)
+		}
+	}()
+	t.Cleanup(ts.Close)
+	return ts
+}
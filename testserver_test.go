@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewTestServer(t *testing.T) {
+	cfg := &config{
+		AssetsDir:     "assets",
+		PageTemplate:  "templates/page.html",
+		IndexTemplate: "templates/index.html",
+		Pages: []page{
+			page{
+				URL:       "/home",
+				Title:     "Home",
+				ImagePath: "images/home.jpg",
+				Zones:     []pageZone{pageZone{Left: 367, Top: 44, Right: 539, Bottom: 263, Target: "/detail"}},
+			},
+			page{
+				URL:       "/detail",
+				Title:     "Detail",
+				ImagePath: "images/detail.jpg",
+				Zones:     []pageZone{pageZone{Left: 436, Top: 31, Right: 538, Bottom: 73, Target: "/home"}},
+			},
+		},
+	}
+	ts := NewTestServer(t, cfg)
+
+	resp, err := ts.Client().Get(ts.URL + "/detail")
+	if err != nil {
+		t.Fatalf("get error: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("got %d want %d", got, want)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("could not read body: %v", err)
+	}
+	if !strings.Contains(string(body), "<title>Detail") {
+		t.Error("body does not contain detail title")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+// thumbnails pre-generates scaled down jpeg versions of the page
+// images, written to a thumbs directory in the images directory, for
+// index templates showing page previews.
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Thumbnail defaults; thumbnails are written to thumbnailDir in the
+// images directory.
+const (
+	defaultThumbnailWidth = 320
+	thumbnailDir          = "thumbs"
+	thumbnailQuality      = 85
+)
+
+// ThumbnailOptions are options for the thumbnails command set from the
+// command line.
+type ThumbnailOptions struct {
+	Width int // thumbnail width in pixels, keeping the image aspect ratio
+
+	scaler draw.Scaler // draw.CatmullRom if nil, replaced for testing
+}
+
+// thumbnailPath returns the path in the assets directory of the
+// thumbnail of imagePath, such as images/thumbs/home.jpg for
+// images/home.png. The directory of the image in the images directory
+// is kept, such as images/thumbs/shop/home.jpg for images/shop/home.png,
+// so that images with the same name in different directories do not
+// share a thumbnail.
+func thumbnailPath(imagesDir, imagePath string) string {
+	rel, ok := strings.CutPrefix(path.Clean(imagePath), path.Clean(imagesDir)+"/")
+	if !ok {
+		rel = path.Clean(imagePath)
+	}
+	name := strings.TrimSuffix(rel, path.Ext(rel))
+	return path.Join(imagesDir, thumbnailDir, name+".jpg")
+}
+
+// writeThumbnails writes a thumbnail of each page image in cfg's
+// AssetsFS to assetsDir on disk, returning the paths written.
+// Thumbnails newer than their image are skipped, as are media which
+// cannot be decoded, such as videos and svg images, with a warning.
+// Images narrower than the thumbnail width are not enlarged.
+func writeThumbnails(cfg *config, assetsDir string, options ThumbnailOptions) ([]string, error) {
+	scaler := options.scaler
+	if scaler == nil {
+		scaler = draw.CatmullRom
+	}
+	var written []string
+	seen := map[string]bool{}
+	for _, pg := range cfg.Pages {
+		if seen[pg.ImagePath] {
+			continue
+		}
+		seen[pg.ImagePath] = true
+		thumb := thumbnailPath(cfg.Dirs.Images, pg.ImagePath)
+		dst := filepath.Join(assetsDir, filepath.FromSlash(thumb))
+
+		srcInfo, err := fs.Stat(cfg.AssetsFS, pg.ImagePath)
+		if err != nil {
+			return written, fmt.Errorf("thumbnail image error: %w", err)
+		}
+		if dstInfo, err := os.Stat(dst); err == nil && !dstInfo.ModTime().Before(srcInfo.ModTime()) {
+			continue
+		}
+
+		src, err := decodeImage(cfg.AssetsFS, pg.ImagePath)
+		if err != nil {
+			log.Printf("thumbnail warning: skipping %q: %v", pg.ImagePath, err)
+			continue
+		}
+		b := src.Bounds()
+		width := min(options.Width, b.Dx())
+		height := max(1, b.Dy()*width/b.Dx())
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		scaler.Scale(img, img.Bounds(), src, b, draw.Src, nil)
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return written, fmt.Errorf("thumbnail directory error: %w", err)
+		}
+		f, err := os.Create(dst)
+		if err != nil {
+			return written, fmt.Errorf("thumbnail create error: %w", err)
+		}
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: thumbnailQuality})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return written, fmt.Errorf("thumbnail write error: %w", err)
+		}
+		written = append(written, thumb)
+	}
+	return written, nil
+}
+
+// decodeImage decodes the image at name in fsys.
+func decodeImage(fsys fs.FS, name string) (image.Image, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	img, _, err := image.Decode(f)
+	return img, err
+}
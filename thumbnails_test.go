@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/image/draw"
+)
+
+// stubScaler counts the images scaled, leaving dst unchanged.
+type stubScaler struct {
+	calls int
+}
+
+func (s *stubScaler) Scale(dst draw.Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op draw.Op, opts *draw.Options) {
+	s.calls++
+}
+
+func TestWriteThumbnails(t *testing.T) {
+	cfg := initServerConfig(t)
+	dir := t.TempDir()
+	scaler := &stubScaler{}
+	options := ThumbnailOptions{Width: 160, scaler: scaler}
+
+	written, err := writeThumbnails(cfg, dir, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"images/thumbs/home.jpg", "images/thumbs/detail.jpg"}
+	if diff := cmp.Diff(want, written); diff != "" {
+		t.Errorf("written mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := scaler.calls, 2; got != want {
+		t.Errorf("got %d scales want %d", got, want)
+	}
+	homeThumb := filepath.Join(dir, "images", "thumbs", "home.jpg")
+	width, _, ok := imageSize(os.DirFS(dir), "images/thumbs/home.jpg")
+	if !ok || width != 160 {
+		t.Errorf("thumbnail width got %d (ok %t) want 160", width, ok)
+	}
+
+	// Up to date thumbnails are skipped; an outdated one is rewritten.
+	old := time.Now().Add(-24 * time.Hour * 365 * 30)
+	if err := os.Chtimes(homeThumb, old, old); err != nil {
+		t.Fatal(err)
+	}
+	written, err = writeThumbnails(cfg, dir, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"images/thumbs/home.jpg"}, written); diff != "" {
+		t.Errorf("rewritten mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := scaler.calls, 3; got != want {
+		t.Errorf("got %d scales want %d", got, want)
+	}
+}
+
+func TestThumbnailPath(t *testing.T) {
+	tests := []struct {
+		imagePath string
+		want      string
+	}{
+		{"images/home.png", "images/thumbs/home.jpg"},
+		{"images/sub/home.png", "images/thumbs/sub/home.jpg"},
+		{"images/other/home.png", "images/thumbs/other/home.jpg"},
+		{"pics/home.png", "images/thumbs/pics/home.jpg"},
+	}
+	for _, tt := range tests {
+		if got := thumbnailPath("images", tt.imagePath); got != tt.want {
+			t.Errorf("%s got %q want %q", tt.imagePath, got, tt.want)
+		}
+	}
+}
+
+// TestWriteThumbnailsCollision checks that images with the same name in
+// different directories are given separate thumbnails.
+func TestWriteThumbnailsCollision(t *testing.T) {
+	cfg := initServerConfig(t)
+	cfg.Pages[0].ImagePath = "images/a/home.jpg"
+	cfg.Pages[1].ImagePath = "images/b/home.jpg"
+	cfg.AssetsFS = fstest.MapFS{
+		"images/a/home.jpg": &fstest.MapFile{Data: testJPEG(t, 40, 20)},
+		"images/b/home.jpg": &fstest.MapFile{Data: testJPEG(t, 20, 40)},
+	}
+	dir := t.TempDir()
+
+	written, err := writeThumbnails(cfg, dir, ThumbnailOptions{Width: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"images/thumbs/a/home.jpg", "images/thumbs/b/home.jpg"}
+	if diff := cmp.Diff(want, written); diff != "" {
+		t.Errorf("written mismatch (-want +got):\n%s", diff)
+	}
+	for _, tt := range []struct {
+		thumb  string
+		height int
+	}{{want[0], 5}, {want[1], 20}} {
+		width, height, ok := imageSize(os.DirFS(dir), tt.thumb)
+		if !ok || width != 10 || height != tt.height {
+			t.Errorf("%s got %dx%d (ok %t) want 10x%d", tt.thumb, width, height, ok, tt.height)
+		}
+	}
+}
+
+// testJPEG returns a jpeg encoded image of the given size.
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,96 @@
+package main
+
+// tls provides automatic Let's Encrypt certificates, by way of
+// golang.org/x/crypto/acme/autocert, for serving public demos on a
+// real domain.
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Ports used for automatic TLS, as required by the ACME challenges.
+const (
+	tlsAutoPort      = "443"
+	tlsAutoHTTPPort  = "80"
+	defaultCertCache = "certs"
+)
+
+// hstsValue is the Strict-Transport-Security header value set with
+// ServerOptions.HSTS, asking browsers to use https for a year.
+const hstsValue = "max-age=31536000"
+
+// tlsVersions map the tlsMinVersion config values to tls versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsCipherSuite returns the id of the cipher suite with the standard
+// name, such as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". Only the
+// suites crypto/tls considers secure are accepted, other than the TLS
+// 1.3 suites, such as "TLS_AES_128_GCM_SHA256", which crypto/tls does
+// not allow to be configured.
+func tlsCipherSuite(name string) (uint16, bool) {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name && !slices.Equal(cs.SupportedVersions, []uint16{tls.VersionTLS13}) {
+			return cs.ID, true
+		}
+	}
+	return 0, false
+}
+
+// newCertManager returns an autocert.Manager which obtains certificates
+// for domains only, caching them in cacheDir.
+func newCertManager(domains []string, cacheDir string) *autocert.Manager {
+	if cacheDir == "" {
+		cacheDir = defaultCertCache
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+}
+
+// hstsMiddleware sets the Strict-Transport-Security header on the
+// responses of the TLS listener. Plaintext requests are redirected to
+// https by the port 80 server started by startHTTPRedirect.
+func hstsMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", hstsValue)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// startHTTPRedirect starts a plaintext http server on port 80 which
+// answers ACME http-01 challenges and redirects all other requests to
+// https. It is closed when the main server shuts down.
+func (s *server) startHTTPRedirect() {
+	redirect := &http.Server{
+		Addr:              net.JoinHostPort(s.serverAddress, tlsAutoHTTPPort),
+		Handler:           s.certManager.HTTPHandler(nil),
+		ReadTimeout:       1 * time.Second,
+		WriteTimeout:      2 * time.Second,
+		IdleTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+	}
+	s.webServer.RegisterOnShutdown(func() {
+		_ = redirect.Close()
+	})
+	go func() {
+		err := redirect.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("http redirect server error: %v", err)
+		}
+	}()
+}
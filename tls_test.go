@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerTLSAuto(t *testing.T) {
+	cfg := initServerConfig(t)
+
+	_, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{TLSAuto: true})
+	if err == nil || !strings.Contains(err.Error(), "requires at least one domain") {
+		t.Fatalf("expected domain error, got %v", err)
+	}
+
+	s, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{
+		TLSAuto:      true,
+		Domains:      []string{"example.com"},
+		CertCacheDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.webServer.Addr, "127.0.0.1:443"; got != want {
+		t.Errorf("addr got %q want %q", got, want)
+	}
+	if s.webServer.TLSConfig == nil || s.webServer.TLSConfig.GetCertificate == nil {
+		t.Fatal("expected a TLSConfig with GetCertificate")
+	}
+
+	// plaintext requests are redirected to https
+	w := httptest.NewRecorder()
+	s.certManager.HTTPHandler(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/home", nil))
+	if got, want := w.Code, http.StatusFound; got != want {
+		t.Errorf("redirect status got %d want %d", got, want)
+	}
+	if got, want := w.Header().Get("Location"), "https://example.com/home"; got != want {
+		t.Errorf("redirect location got %q want %q", got, want)
+	}
+}
+
+// TestServerTLSConfig checks that the config tls minimum version and
+// ciphers are validated and applied to the server's TLSConfig.
+func TestServerTLSConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		ciphers  []string
+		wantMin  uint16
+		wantCode ErrorCode
+	}{
+		{name: "default"},
+		{name: "1.3", version: "1.3", wantMin: tls.VersionTLS13},
+		{name: "1.2 with ciphers", version: "1.2", ciphers: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}, wantMin: tls.VersionTLS12},
+		{name: "bad version", version: "1.4", wantCode: CodeInvalidTLS},
+		{name: "insecure cipher", ciphers: []string{"TLS_RSA_WITH_RC4_128_SHA"}, wantCode: CodeInvalidTLS},
+		{name: "1.3 cipher", ciphers: []string{"TLS_AES_128_GCM_SHA256"}, wantCode: CodeInvalidTLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := initServerConfig(t)
+			cfg.TLSMinVersion = tt.version
+			cfg.TLSCiphers = tt.ciphers
+			err := cfg.validateConfig()
+			if tt.wantCode != "" {
+				var eic ErrInvalidConfig
+				if !errors.As(err, &eic) || eic.Code != tt.wantCode {
+					t.Errorf("expected %s error, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := newServer("127.0.0.1", "8001", cfg, ServerOptions{
+				TLSAuto:      true,
+				Domains:      []string{"example.com"},
+				CertCacheDir: t.TempDir(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := s.webServer.TLSConfig.MinVersion, tt.wantMin; got != want {
+				t.Errorf("min version got %x want %x", got, want)
+			}
+			if got, want := len(s.webServer.TLSConfig.CipherSuites), len(tt.ciphers); got != want {
+				t.Errorf("got %d cipher suites want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestHSTSMiddleware(t *testing.T) {
+	handler := hstsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/home", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Errorf("https status got %d want %d", got, want)
+	}
+	if got, want := w.Header().Get("Strict-Transport-Security"), hstsValue; got != want {
+		t.Errorf("hsts header got %q want %q", got, want)
+	}
+}
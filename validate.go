@@ -0,0 +1,63 @@
+package main
+
+// validate provides a machine-readable JSON report of the result of
+// validating a configuration, for use by editors and CI.
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// errValidationReported is returned by Validate in JSON mode if the
+// config is invalid, the problems having already been reported as
+// JSON.
+var errValidationReported = errors.New("config invalid")
+
+// validationError is the JSON representation of a validation problem.
+// Code is empty for problems that are not an ErrInvalidConfig.
+type validationError struct {
+	Code    ErrorCode `json:"code,omitempty"`
+	Message string    `json:"message"`
+}
+
+// validationReport is the JSON representation of a validation result.
+type validationReport struct {
+	Config string            `json:"config"`
+	Valid  bool              `json:"valid"`
+	Errors []validationError `json:"errors"`
+}
+
+// newValidationErrors converts err, which may join several errors, to
+// its JSON representation.
+func newValidationErrors(err error) []validationError {
+	if err == nil {
+		return []validationError{}
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	ves := make([]validationError, 0, len(errs))
+	for _, e := range errs {
+		ve := validationError{Message: e.Error()}
+		var eic ErrInvalidConfig
+		if errors.As(e, &eic) {
+			ve.Code = eic.Code
+		}
+		ves = append(ves, ve)
+	}
+	return ves
+}
+
+// writeValidation writes the JSON validation report for configFile,
+// where err is the result of validation.
+func writeValidation(w io.Writer, configFile string, err error) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(validationReport{
+		Config: configFile,
+		Valid:  err == nil,
+		Errors: newValidationErrors(err),
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want validationReport
+	}{
+		{
+			name: "ok",
+			err:  nil,
+			want: validationReport{Config: "config.yaml", Valid: true, Errors: []validationError{}},
+		},
+		{
+			name: "joined",
+			err: errors.Join(
+				ErrInvalidConfig{CodeDuplicateURL, "dup"},
+				errors.New("other"),
+			),
+			want: validationReport{Config: "config.yaml", Errors: []validationError{
+				{Code: CodeDuplicateURL, Message: "invalid config or template: dup"},
+				{Message: "other"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeValidation(&buf, "config.yaml", tt.err); err != nil {
+				t.Fatal(err)
+			}
+			var got validationReport
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// TestValidateAllErrorsCodes checks that each of several config problems
+// is reported with its code.
+func TestValidateAllErrorsCodes(t *testing.T) {
+	config := `
+---
+assetsDir: "assets"
+pageTemplate: "templates/page.html"
+indexTemplate: "templates/index.html"
+pages:
+  -
+    URL: "/home"
+    Title: ""
+    ImagePath: "images/home.jpg"
+    Zones:
+      -
+        Left:   367
+        Top:    44
+        Right:  539
+        Bottom: 263
+        Target: "/nowhere"
+  -
+    URL: "/home"
+    Title: "Detail"
+    ImagePath: "images/detail.jpg"
+    Zones:
+      -
+        Left: 436
+        Top:  31
+        Right: 538
+        Bottom: 73
+        Target: "/home"
+`
+	_, err := newConfigAllErrors([]byte(config), false)
+	var got []ErrorCode
+	for _, ve := range newValidationErrors(err) {
+		got = append(got, ve.Code)
+	}
+	want := []ErrorCode{CodeMissingField, CodeDuplicateURL, CodeInvalidTarget}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error(diff)
+	}
+}